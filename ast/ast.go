@@ -10,6 +10,8 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // position of the first character of the node
+	End() token.Position // position of the first character after the node
 }
 
 type Statement interface {
@@ -43,20 +45,60 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
+
 type TypeDeclarationStatement struct {
 	Token token.Token // 'type' token
 	Name  *Identifier
 	Value Expression
+
+	// Labelled is set for declarations written
+	// as a label, Name: type = ..., rather than
+	// type Name = ...
+	Labelled bool
 }
 
 func (tds *TypeDeclarationStatement) statementNode()       {}
 func (tds *TypeDeclarationStatement) TokenLiteral() string { return tds.Token.Literal }
+func (tds *TypeDeclarationStatement) Pos() token.Position {
+	if tds.Labelled && tds.Name != nil {
+		return tds.Name.Pos()
+	}
+	return tds.Token.Pos()
+}
+func (tds *TypeDeclarationStatement) End() token.Position {
+	if tds.Value != nil {
+		return tds.Value.End()
+	}
+	if tds.Name != nil {
+		return tds.Name.End()
+	}
+	return tds.Token.End()
+}
 func (tds *TypeDeclarationStatement) String() string {
 	var out bytes.Buffer
 
-	out.WriteString(tds.TokenLiteral())
-	out.WriteRune(' ')
-	out.WriteString(tds.Name.String())
+	if tds.Labelled {
+		out.WriteString(tds.Name.String())
+		out.WriteString(": ")
+		out.WriteString(tds.TokenLiteral())
+	} else {
+		out.WriteString(tds.TokenLiteral())
+		out.WriteRune(' ')
+		out.WriteString(tds.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if tds.Value != nil {
@@ -75,6 +117,8 @@ type Identifier struct {
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string       { return i.Value }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos() }
+func (i *Identifier) End() token.Position  { return i.Token.End() }
 
 type ReturnStatement struct {
 	Token       token.Token // 'return' token
@@ -83,6 +127,13 @@ type ReturnStatement struct {
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position  { return rs.Token.Pos() }
+func (rs *ReturnStatement) End() token.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+	return rs.Token.End()
+}
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -108,6 +159,13 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position  { return es.Token.Pos() }
+func (es *ExpressionStatement) End() token.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+	return es.Token.End()
+}
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -123,6 +181,8 @@ type IntegerLiteral struct {
 func (lit *IntegerLiteral) expressionNode()      {}
 func (lit *IntegerLiteral) TokenLiteral() string { return lit.Token.Literal }
 func (lit *IntegerLiteral) String() string       { return lit.Token.Literal }
+func (lit *IntegerLiteral) Pos() token.Position  { return lit.Token.Pos() }
+func (lit *IntegerLiteral) End() token.Position  { return lit.Token.End() }
 
 type PrefixExpression struct {
 	Token    token.Token // prefix tokens: !, -, *
@@ -132,6 +192,13 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos() }
+func (pe *PrefixExpression) End() token.Position {
+	if pe.Right != nil {
+		return pe.Right.End()
+	}
+	return pe.Token.End()
+}
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 
@@ -152,6 +219,18 @@ type InfixExpression struct {
 
 func (ie InfixExpression) expressionNode()      {}
 func (ie InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie InfixExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos()
+}
+func (ie InfixExpression) End() token.Position {
+	if ie.Right != nil {
+		return ie.Right.End()
+	}
+	return ie.Token.End()
+}
 func (ie InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -174,6 +253,208 @@ type Boolean struct {
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position  { return b.Token.Pos() }
+func (b *Boolean) End() token.Position  { return b.Token.End() }
+
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (lit *FloatLiteral) expressionNode()      {}
+func (lit *FloatLiteral) TokenLiteral() string { return lit.Token.Literal }
+func (lit *FloatLiteral) String() string       { return lit.Token.Literal }
+func (lit *FloatLiteral) Pos() token.Position  { return lit.Token.Pos() }
+func (lit *FloatLiteral) End() token.Position  { return lit.Token.End() }
+
+type StringLiteral struct {
+	Token token.Token
+	Value string // with the escapes resolved
+}
+
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) String() string       { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos() }
+func (sl *StringLiteral) End() token.Position  { return sl.Token.End() }
+
+// ArrayLiteral is [a, b, ...c], the elements of
+// which may spread in those of other lists
+type ArrayLiteral struct {
+	Token    token.Token // [
+	Elements []Expression
+	Rbrack   token.Token // ]
+}
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position  { return al.Token.Pos() }
+func (al *ArrayLiteral) End() token.Position  { return al.Rbrack.End() }
+func (al *ArrayLiteral) String() string {
+	elements := []string{}
+	for _, e := range al.Elements {
+		elements = append(elements, e.String())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// IndexExpression is xs[i]
+type IndexExpression struct {
+	Token  token.Token // [
+	Left   Expression
+	Index  Expression
+	Rbrack token.Token // ]
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return ie.Left.Pos() }
+func (ie *IndexExpression) End() token.Position  { return ie.Rbrack.End() }
+func (ie *IndexExpression) String() string {
+	return ie.Left.String() + "[" + ie.Index.String() + "]"
+}
+
+// RangeExpression is [Start..Stop], or [Start..<Stop]
+// leaving Stop out, with an optional second element
+// to set the step, as in [1, 3..9]
+type RangeExpression struct {
+	Token     token.Token // [
+	Start     Expression
+	Next      Expression // nil when stepping by one
+	Stop      Expression
+	Exclusive bool
+	Rbrack    token.Token // ]
+}
+
+func (re *RangeExpression) expressionNode()      {}
+func (re *RangeExpression) TokenLiteral() string { return re.Token.Literal }
+func (re *RangeExpression) Pos() token.Position  { return re.Token.Pos() }
+func (re *RangeExpression) End() token.Position  { return re.Rbrack.End() }
+func (re *RangeExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteRune('[')
+	out.WriteString(re.Start.String())
+	if re.Next != nil {
+		out.WriteString(", ")
+		out.WriteString(re.Next.String())
+	}
+	out.WriteString("..")
+	if re.Exclusive {
+		out.WriteRune('<')
+	}
+	out.WriteString(re.Stop.String())
+	out.WriteRune(']')
+
+	return out.String()
+}
+
+// SpreadExpression is ...x, which splices
+// the contents of x into a record or list
+type SpreadExpression struct {
+	Token token.Token // ...
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) Pos() token.Position  { return se.Token.Pos() }
+func (se *SpreadExpression) End() token.Position {
+	if se.Value != nil {
+		return se.Value.End()
+	}
+	return se.Token.End()
+}
+func (se *SpreadExpression) String() string {
+	return se.Token.Literal + se.Value.String()
+}
+
+// RecordLiteral is {name: value, ...other}. Its
+// entries are RecordFields and SpreadExpressions,
+// in the order they were written
+type RecordLiteral struct {
+	Token   token.Token // {
+	Entries []Expression
+	Rbrace  token.Token // }
+}
+
+func (rl *RecordLiteral) expressionNode()      {}
+func (rl *RecordLiteral) TokenLiteral() string { return rl.Token.Literal }
+func (rl *RecordLiteral) Pos() token.Position  { return rl.Token.Pos() }
+func (rl *RecordLiteral) End() token.Position  { return rl.Rbrace.End() }
+func (rl *RecordLiteral) String() string {
+	entries := []string{}
+	for _, entry := range rl.Entries {
+		entries = append(entries, entry.String())
+	}
+
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// HashLiteral is {key: value, ...}, where the keys are
+// expressions rather than names, as in a record
+type HashLiteral struct {
+	Token  token.Token // {
+	Keys   []Expression
+	Values []Expression
+	Rbrace token.Token // }
+}
+
+func (hl *HashLiteral) expressionNode()      {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos() }
+func (hl *HashLiteral) End() token.Position  { return hl.Rbrace.End() }
+func (hl *HashLiteral) String() string {
+	pairs := []string{}
+	for i, key := range hl.Keys {
+		// a name as the key would read as a record
+		k := key.String()
+		if _, ok := key.(*Identifier); ok {
+			k = "(" + k + ")"
+		}
+		pairs = append(pairs, k+": "+hl.Values[i].String())
+	}
+
+	// {} would read as the empty record
+	if len(pairs) == 0 {
+		return "{:}"
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// RecordField is a name: value entry of a record literal
+type RecordField struct {
+	Name  *Identifier
+	Value Expression
+}
+
+func (rf *RecordField) expressionNode()      {}
+func (rf *RecordField) TokenLiteral() string { return rf.Name.TokenLiteral() }
+func (rf *RecordField) Pos() token.Position  { return rf.Name.Pos() }
+func (rf *RecordField) End() token.Position  { return rf.Value.End() }
+func (rf *RecordField) String() string {
+	// the shorthand of a record pattern
+	if rf.Value == Expression(rf.Name) {
+		return rf.Name.String()
+	}
+	return rf.Name.String() + ": " + rf.Value.String()
+}
+
+// MemberExpression is r.field
+type MemberExpression struct {
+	Token    token.Token // .
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) Pos() token.Position  { return me.Object.Pos() }
+func (me *MemberExpression) End() token.Position  { return me.Property.End() }
+func (me *MemberExpression) String() string {
+	return me.Object.String() + "." + me.Property.String()
+}
 
 type IfExpression struct {
 	Token       token.Token // if token
@@ -184,6 +465,16 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Pos() }
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	if ie.Consequence != nil {
+		return ie.Consequence.End()
+	}
+	return ie.Token.End()
+}
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -200,13 +491,77 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// SwitchExpression is switch (Value) { pattern => result, ... },
+// which gives the result of the first arm whose pattern
+// matches the value
+type SwitchExpression struct {
+	Token  token.Token // switch
+	Value  Expression
+	Arms   []*SwitchArm
+	Rbrace token.Token // }
+}
+
+func (se *SwitchExpression) expressionNode()      {}
+func (se *SwitchExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SwitchExpression) Pos() token.Position  { return se.Token.Pos() }
+func (se *SwitchExpression) End() token.Position  { return se.Rbrace.End() }
+func (se *SwitchExpression) String() string {
+	arms := []string{}
+	for _, arm := range se.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	return "switch (" + se.Value.String() + ") {" + strings.Join(arms, ", ") + "}"
+}
+
+// SwitchArm is a pattern => result arm of a switch.
+// The pattern is a literal, a type name, a record
+// pattern, or a name to bind the value to, where _
+// binds nothing
+type SwitchArm struct {
+	Pattern Expression
+	Result  Expression
+}
+
+func (sa *SwitchArm) Pos() token.Position { return sa.Pattern.Pos() }
+func (sa *SwitchArm) End() token.Position { return sa.Result.End() }
+func (sa *SwitchArm) String() string {
+	return sa.Pattern.String() + " => " + sa.Result.String()
+}
+
+// RecordPattern is {name: pattern, ...} in a switch arm,
+// matching records having those fields, with values
+// matching the patterns. A field written alone, as in
+// {name}, binds the value of the field to its name
+type RecordPattern struct {
+	Token  token.Token // {
+	Fields []*RecordField
+	Rbrace token.Token // }
+}
+
+func (rp *RecordPattern) expressionNode()      {}
+func (rp *RecordPattern) TokenLiteral() string { return rp.Token.Literal }
+func (rp *RecordPattern) Pos() token.Position  { return rp.Token.Pos() }
+func (rp *RecordPattern) End() token.Position  { return rp.Rbrace.End() }
+func (rp *RecordPattern) String() string {
+	fields := []string{}
+	for _, field := range rp.Fields {
+		fields = append(fields, field.String())
+	}
+
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+
 type BlockStatement struct {
 	Token      token.Token // { token
 	Statements []Statement
+	Rbrace     token.Token // } token
 }
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Pos() }
+func (bs *BlockStatement) End() token.Position  { return bs.Rbrace.End() }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -221,22 +576,42 @@ type FunctionLiteral struct {
 	Token     token.Token // func
 	Arguments []*Identifier
 	Body      *BlockStatement
+
+	// optional annotations, ArgumentTypes lines up with
+	// Arguments and is nil where an argument has none
+	ArgumentTypes []Expression
+	ReturnType    Expression
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos() }
+func (fl *FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+	return fl.Token.End()
+}
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
 	args := []string{}
-	for _, arg := range fl.Arguments {
-		args = append(args, arg.String())
+	for i, arg := range fl.Arguments {
+		if i < len(fl.ArgumentTypes) && fl.ArgumentTypes[i] != nil {
+			args = append(args, arg.String()+": "+fl.ArgumentTypes[i].String())
+		} else {
+			args = append(args, arg.String())
+		}
 	}
 
 	out.WriteString(fl.TokenLiteral())
 	out.WriteRune('(')
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteRune(')')
+	if fl.ReturnType != nil {
+		out.WriteString(": ")
+		out.WriteString(fl.ReturnType.String())
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
@@ -246,10 +621,42 @@ type InvocationExpression struct {
 	Token     token.Token // ( token
 	Function  Expression  // Identifier || FunctionLiteral
 	Arguments []Expression
+	Rparen    token.Token // ) token, or the last one of a pipe
+
+	// written in parentheses, so that a pipe
+	// calls it rather than adding to its arguments
+	Grouped bool
 }
 
 func (ie InvocationExpression) expressionNode()      {}
 func (ie InvocationExpression) TokenLiteral() string { return ie.Token.Literal }
+
+// Pos and End cover the arguments as well, as
+// a pipe puts the last argument before the rest
+func (ie InvocationExpression) Pos() token.Position {
+	pos := ie.Token.Pos()
+	if ie.Function != nil {
+		pos = ie.Function.Pos()
+	}
+	for _, arg := range ie.Arguments {
+		if arg != nil && arg.Pos().Offset < pos.Offset {
+			pos = arg.Pos()
+		}
+	}
+	return pos
+}
+func (ie InvocationExpression) End() token.Position {
+	end := ie.Rparen.End()
+	for _, arg := range ie.Arguments {
+		if arg != nil && arg.End().Offset > end.Offset {
+			end = arg.End()
+		}
+	}
+	if ie.Function != nil && ie.Function.End().Offset > end.Offset {
+		end = ie.Function.End()
+	}
+	return end
+}
 func (ie InvocationExpression) String() string {
 	var out bytes.Buffer
 
@@ -270,16 +677,31 @@ func (ie InvocationExpression) String() string {
 type LetStatement struct {
 	Token token.Token // the token.LET token
 	Name  *Identifier
+	Type  Expression // optional annotation
 	Value Expression
 }
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position  { return ls.Token.Pos() }
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+	return ls.Token.End()
+}
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
 	out.WriteString(ls.Name.String())
+	if ls.Type != nil {
+		out.WriteString(": ")
+		out.WriteString(ls.Type.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -290,3 +712,156 @@ func (ls *LetStatement) String() string {
 
 	return out.String()
 }
+
+// IntersectionType composes several types
+// into one, written A & B & C
+type IntersectionType struct {
+	Token token.Token // the first & token
+	Types []Expression
+}
+
+func (it *IntersectionType) expressionNode()      {}
+func (it *IntersectionType) TokenLiteral() string { return it.Token.Literal }
+func (it *IntersectionType) Pos() token.Position {
+	if len(it.Types) > 0 && it.Types[0] != nil {
+		return it.Types[0].Pos()
+	}
+	return it.Token.Pos()
+}
+func (it *IntersectionType) End() token.Position {
+	if len(it.Types) > 0 && it.Types[len(it.Types)-1] != nil {
+		return it.Types[len(it.Types)-1].End()
+	}
+	return it.Token.End()
+}
+func (it *IntersectionType) String() string {
+	types := []string{}
+	for _, t := range it.Types {
+		types = append(types, t.String())
+	}
+
+	return strings.Join(types, " & ")
+}
+
+// PropertyType is a named member of a type,
+// written name: Type, or .name: Type when
+// using the dot notation
+type PropertyType struct {
+	Token token.Token // the name token, or the . token
+	Name  *Identifier
+	Type  Expression
+}
+
+func (pt *PropertyType) expressionNode()      {}
+func (pt *PropertyType) TokenLiteral() string { return pt.Token.Literal }
+func (pt *PropertyType) Pos() token.Position  { return pt.Token.Pos() }
+func (pt *PropertyType) End() token.Position {
+	if pt.Type != nil {
+		return pt.Type.End()
+	}
+	return pt.Name.End()
+}
+func (pt *PropertyType) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(pt.Name.String())
+	out.WriteString(": ")
+	if pt.Type != nil {
+		out.WriteString(pt.Type.String())
+	}
+
+	return out.String()
+}
+
+// UnionType is a sum of variants, usually
+// written with a pipe leading each variant:
+//
+//	type StatusCode =
+//	    | InformationalCode
+//	    | SuccessCode
+type UnionType struct {
+	Token    token.Token // the first | token
+	Variants []*UnionVariant
+}
+
+func (ut *UnionType) expressionNode()      {}
+func (ut *UnionType) TokenLiteral() string { return ut.Token.Literal }
+func (ut *UnionType) Pos() token.Position {
+	if len(ut.Variants) > 0 {
+		return ut.Variants[0].Pos()
+	}
+	return ut.Token.Pos()
+}
+func (ut *UnionType) End() token.Position {
+	if len(ut.Variants) > 0 {
+		return ut.Variants[len(ut.Variants)-1].End()
+	}
+	return ut.Token.End()
+}
+func (ut *UnionType) String() string {
+	var out bytes.Buffer
+
+	for i, v := range ut.Variants {
+		if i > 0 {
+			out.WriteRune(' ')
+		}
+		out.WriteString(v.String())
+	}
+
+	return out.String()
+}
+
+type UnionVariant struct {
+	Token token.Token // the | token, or the first token of the type if there was none
+	Type  Expression
+}
+
+func (uv *UnionVariant) expressionNode()      {}
+func (uv *UnionVariant) TokenLiteral() string { return uv.Token.Literal }
+func (uv *UnionVariant) Pos() token.Position  { return uv.Token.Pos() }
+func (uv *UnionVariant) End() token.Position {
+	if uv.Type != nil {
+		return uv.Type.End()
+	}
+	return uv.Token.End()
+}
+func (uv *UnionVariant) String() string {
+	if uv.Type != nil {
+		return "| " + uv.Type.String()
+	}
+	return "|"
+}
+
+// LiteralType is a singleton type whose only
+// value is the literal, as in type Ok = 200
+type LiteralType struct {
+	Token token.Token // the literal token
+	Value Expression  // IntegerLiteral || Boolean
+}
+
+func (lt *LiteralType) expressionNode()      {}
+func (lt *LiteralType) TokenLiteral() string { return lt.Token.Literal }
+func (lt *LiteralType) Pos() token.Position  { return lt.Token.Pos() }
+func (lt *LiteralType) End() token.Position  { return lt.Value.End() }
+func (lt *LiteralType) String() string       { return lt.Value.String() }
+
+// FunctionType is the type of a function,
+// written func(int, int): int
+type FunctionType struct {
+	Token  token.Token // func
+	Params []Expression
+	Result Expression
+}
+
+func (ft *FunctionType) expressionNode()      {}
+func (ft *FunctionType) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FunctionType) Pos() token.Position  { return ft.Token.Pos() }
+func (ft *FunctionType) End() token.Position  { return ft.Result.End() }
+func (ft *FunctionType) String() string {
+	params := []string{}
+	for _, p := range ft.Params {
+		params = append(params, p.String())
+	}
+
+	return "func(" + strings.Join(params, ", ") + "): " + ft.Result.String()
+}
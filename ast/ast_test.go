@@ -26,3 +26,27 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() was not correct, received %q", program.String())
 	}
 }
+
+func TestNodePositions(t *testing.T) {
+	at := func(offset int) token.Span {
+		return token.Span{
+			Start: token.Position{Offset: offset, Line: 1, Column: offset + 1},
+			End:   token.Position{Offset: offset + 1, Line: 1, Column: offset + 2},
+		}
+	}
+
+	// a + b
+	infix := &InfixExpression{
+		Token:    token.Token{TokenKind: token.SUM, Literal: "+", Span: at(2)},
+		Left:     &Identifier{Token: token.Token{TokenKind: token.IDENT, Literal: "a", Span: at(0)}, Value: "a"},
+		Operator: "+",
+		Right:    &Identifier{Token: token.Token{TokenKind: token.IDENT, Literal: "b", Span: at(4)}, Value: "b"},
+	}
+
+	if infix.Pos().Offset != 0 {
+		t.Errorf("infix.Pos() was not correct, received %+v", infix.Pos())
+	}
+	if infix.End().Offset != 5 {
+		t.Errorf("infix.End() was not correct, received %+v", infix.End())
+	}
+}
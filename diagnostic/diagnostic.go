@@ -0,0 +1,91 @@
+package diagnostic
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/SCKelemen/oak/token"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Info
+)
+
+var severities = [...]string{
+	Error:   "error",
+	Warning: "warning",
+	Info:    "info",
+}
+
+func (sev Severity) String() string {
+	s := ""
+	if 0 <= sev && sev < Severity(len(severities)) {
+		s = severities[sev]
+	}
+	if s == "" {
+		s = "severity(" + strconv.Itoa(int(sev)) + ")"
+	}
+	return s
+}
+
+// Diagnostic is a single problem found in the
+// source, with enough detail for tools to act
+// on it and for Render to show it to a human
+type Diagnostic struct {
+	Severity Severity
+	Span     token.Span
+	Code     string // short, stable identifier such as P0001
+	Message  string
+
+	// for unexpected token errors, what
+	// we wanted and what we got instead
+	Expected []token.TokenKind
+	Found    token.TokenKind
+
+	Notes       []Note
+	Suggestions []string
+}
+
+// Note adds context to a diagnostic, optionally
+// pointing at another place in the source
+type Note struct {
+	Span    token.Span
+	Message string
+}
+
+func (d Diagnostic) Pos() token.Position { return d.Span.Start }
+
+// String returns the diagnostic on a single
+// line, prefixed with where it happened
+func (d Diagnostic) String() string {
+	var out bytes.Buffer
+
+	if d.Span.IsValid() {
+		out.WriteString(d.Span.Start.String())
+		out.WriteString(": ")
+	}
+	if d.Severity != Error {
+		out.WriteString(d.Severity.String())
+		out.WriteString(": ")
+	}
+	out.WriteString(d.Message)
+
+	return out.String()
+}
+
+func (d Diagnostic) Error() string { return d.String() }
+
+// HasErrors reports whether any of the
+// diagnostics is of Error severity
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
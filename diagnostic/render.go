@@ -0,0 +1,145 @@
+package diagnostic
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/oak/token"
+)
+
+// Render writes d to w in the style of rustc,
+// quoting the offending line of source and
+// underlining the span with carets:
+//
+//	error[P0001]: expected next token to be 'IDENTITY', received =
+//	 --> 2:5
+//	  |
+//	2 | let = 10;
+//	  |     ^ expected IDENTITY, found =
+//	  |
+//	  = help: ...
+func Render(w io.Writer, source string, d Diagnostic) {
+	lines := strings.Split(source, "\n")
+
+	// the gutter must fit the widest line number
+	width := len(strconv.Itoa(d.Span.Start.Line))
+	for _, note := range d.Notes {
+		if n := len(strconv.Itoa(note.Span.Start.Line)); n > width {
+			width = n
+		}
+	}
+	gutter := strings.Repeat(" ", width)
+
+	fmt.Fprintf(w, "%s", d.Severity)
+	if d.Code != "" {
+		fmt.Fprintf(w, "[%s]", d.Code)
+	}
+	fmt.Fprintf(w, ": %s\n", d.Message)
+
+	// notes on the line we just quoted only
+	// need another row of carets under it
+	quoted := 0
+	if d.Span.IsValid() {
+		fmt.Fprintf(w, "%s--> %s\n", gutter, d.Span.Start)
+		fmt.Fprintf(w, "%s |\n", gutter)
+		renderSnippet(w, lines, gutter, d.Span, d.label(), true)
+		quoted = d.Span.Start.Line
+	}
+
+	for _, note := range d.Notes {
+		if note.Span.IsValid() {
+			if note.Span.Start.Line != quoted {
+				fmt.Fprintf(w, "%s |\n", gutter)
+			}
+			renderSnippet(w, lines, gutter, note.Span, note.Message, note.Span.Start.Line != quoted)
+			quoted = note.Span.Start.Line
+			continue
+		}
+		fmt.Fprintf(w, "%s |\n", gutter)
+		fmt.Fprintf(w, "%s = note: %s\n", gutter, note.Message)
+	}
+
+	for _, suggestion := range d.Suggestions {
+		fmt.Fprintf(w, "%s |\n", gutter)
+		fmt.Fprintf(w, "%s = help: %s\n", gutter, suggestion)
+	}
+	fmt.Fprintln(w)
+}
+
+// RenderAll renders every diagnostic in turn
+func RenderAll(w io.Writer, source string, diags []Diagnostic) {
+	for _, d := range diags {
+		Render(w, source, d)
+	}
+}
+
+// label is the short text printed next to the carets
+func (d Diagnostic) label() string {
+	if len(d.Expected) == 0 {
+		return ""
+	}
+
+	expected := make([]string, len(d.Expected))
+	for i, kind := range d.Expected {
+		expected[i] = kind.String()
+	}
+	return fmt.Sprintf("expected %s, found %s", strings.Join(expected, " or "), d.Found)
+}
+
+func renderSnippet(w io.Writer, lines []string, gutter string, span token.Span, label string, quote bool) {
+	start := span.Start
+	if start.Line > len(lines) {
+		return
+	}
+	line := lines[start.Line-1]
+
+	// spans running over several lines are
+	// underlined to the end of the first one
+	end := len(line) + 1
+	if span.End.Line == start.Line && span.End.Column > start.Column {
+		end = span.End.Column
+	}
+	col := start.Column
+	if col > len(line)+1 {
+		col = len(line) + 1
+	}
+	if end > len(line)+1 {
+		end = len(line) + 1
+	}
+
+	// columns count bytes, but we want
+	// a caret for every character
+	carets := 0
+	if end > col {
+		carets = utf8.RuneCountInString(line[col-1 : end-1])
+	}
+	if carets < 1 {
+		carets = 1
+	}
+
+	if quote {
+		fmt.Fprintf(w, "%*d | %s\n", len(gutter), start.Line, line)
+	}
+	fmt.Fprintf(w, "%s | %s%s", gutter, indent(line[:col-1]), strings.Repeat("^", carets))
+	if label != "" {
+		fmt.Fprintf(w, " %s", label)
+	}
+	fmt.Fprintln(w)
+}
+
+// indent blanks out prefix while keeping its
+// tabs, so the carets line up with the source
+func indent(prefix string) string {
+	var out strings.Builder
+	for _, ch := range prefix {
+		if ch == '\t' {
+			out.WriteRune('\t')
+		} else {
+			out.WriteRune(' ')
+		}
+	}
+	return out.String()
+}
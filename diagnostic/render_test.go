@@ -0,0 +1,138 @@
+package diagnostic
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/SCKelemen/oak/token"
+)
+
+func TestRender(t *testing.T) {
+	source := "let x = 5;\nlet = 10;"
+	d := Diagnostic{
+		Severity: Error,
+		Span: token.Span{
+			Start: token.Position{Offset: 15, Line: 2, Column: 5},
+			End:   token.Position{Offset: 16, Line: 2, Column: 6},
+		},
+		Code:        "P0001",
+		Message:     "expected next token to be 'IDENTITY', received =",
+		Expected:    []token.TokenKind{token.IDENT},
+		Found:       token.ASSIGN,
+		Suggestions: []string{"give the binding a name"},
+	}
+
+	expected := `error[P0001]: expected next token to be 'IDENTITY', received =
+ --> 2:5
+  |
+2 | let = 10;
+  |     ^ expected IDENTITY, found =
+  |
+  = help: give the binding a name
+
+`
+
+	var out bytes.Buffer
+	Render(&out, source, d)
+	if out.String() != expected {
+		t.Errorf("Render() was not correct.\nexpected:\n%s\nreceived:\n%s", expected, out.String())
+	}
+}
+
+func TestRenderKeepsTabs(t *testing.T) {
+	source := "\tfoo + bar"
+	d := Diagnostic{
+		Severity: Warning,
+		Span: token.Span{
+			Start: token.Position{Offset: 7, Line: 1, Column: 8},
+			End:   token.Position{Offset: 10, Line: 1, Column: 11},
+		},
+		Message: "unused",
+	}
+
+	expected := "warning: unused\n --> 1:8\n  |\n1 | \tfoo + bar\n  | \t      ^^^\n\n"
+
+	var out bytes.Buffer
+	Render(&out, source, d)
+	if out.String() != expected {
+		t.Errorf("Render() was not correct.\nexpected:\n%q\nreceived:\n%q", expected, out.String())
+	}
+}
+
+func TestRenderMultibyte(t *testing.T) {
+	// columns count bytes, "é" takes two of them
+	source := `let café = "ŐAK" + 1`
+	d := Diagnostic{
+		Severity: Error,
+		Span: token.Span{
+			Start: token.Position{Offset: 12, Line: 1, Column: 13},
+			End:   token.Position{Offset: 23, Line: 1, Column: 24},
+		},
+		Message: "mismatched types",
+	}
+
+	expected := "error: mismatched types\n --> 1:13\n  |\n1 | " + source + "\n  |            ^^^^^^^^^\n\n"
+
+	var out bytes.Buffer
+	Render(&out, source, d)
+	if out.String() != expected {
+		t.Errorf("Render() was not correct.\nexpected:\n%q\nreceived:\n%q", expected, out.String())
+	}
+}
+
+func TestString(t *testing.T) {
+	d := Diagnostic{
+		Severity: Warning,
+		Span:     token.Span{Start: token.Position{Filename: "a.oak", Line: 3, Column: 4}},
+		Message:  "unreachable",
+	}
+
+	if d.String() != "a.oak:3:4: warning: unreachable" {
+		t.Errorf("String() was not correct, received %q", d.String())
+	}
+}
+
+func TestRenderNotes(t *testing.T) {
+	source := "let a = true\na + 1"
+	at := func(line, col, width int) token.Span {
+		return token.Span{
+			Start: token.Position{Line: line, Column: col},
+			End:   token.Position{Line: line, Column: col + width},
+		}
+	}
+	d := Diagnostic{
+		Severity: Error,
+		Span:     at(2, 1, 5),
+		Code:     "T0001",
+		Message:  "mismatched types bool and int",
+		Notes: []Note{
+			{Span: at(1, 9, 4), Message: "bool because of this"},
+			{Span: at(2, 1, 1), Message: "this is of type bool"},
+			{Span: at(2, 5, 1), Message: "this is of type int"},
+			{Message: "no span here"},
+		},
+	}
+
+	expected := `error[T0001]: mismatched types bool and int
+ --> 2:1
+  |
+2 | a + 1
+  | ^^^^^
+  |
+1 | let a = true
+  |         ^^^^ bool because of this
+  |
+2 | a + 1
+  | ^ this is of type bool
+  |     ^ this is of type int
+  |
+  = note: no span here
+
+`
+
+	var out bytes.Buffer
+	Render(&out, source, d)
+	if out.String() != expected {
+		t.Errorf("Render() was not correct.\nexpected:\n%s\nreceived:\n%s", expected, out.String())
+	}
+}
@@ -0,0 +1,476 @@
+package evaluator
+
+import (
+	"math"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/oak/object"
+	"github.com/SCKelemen/oak/token"
+)
+
+// builtins are looked up when a name isn't bound
+// in any environment, so programs may shadow them.
+// Like the README's pipes want, the collection is
+// always the last argument.
+//
+// Given arrays, the collection builtins give back
+// arrays. Given anything else they can iterate,
+// such as a range, they give back a sequence which
+// does the work only as its elements are wanted
+var builtins map[string]*object.Builtin
+
+// builtins call back into the evaluator, so they're
+// set up in init to break the initialization cycle
+func init() {
+	builtins = map[string]*object.Builtin{
+		"map":       {Name: "map", Fn: builtinMap},
+		"filter":    {Name: "filter", Fn: builtinFilter},
+		"reduce":    {Name: "reduce", Fn: builtinReduce},
+		"take":      {Name: "take", Fn: builtinTake},
+		"limit":     {Name: "limit", Fn: builtinLimit},
+		"zip":       {Name: "zip", Fn: builtinZip},
+		"transform": {Name: "transform", Fn: builtinTransform},
+		"len":       {Name: "len", Fn: builtinLen},
+		"first":     {Name: "first", Fn: builtinFirst},
+		"rest":      {Name: "rest", Fn: builtinRest},
+		"push":      {Name: "push", Fn: builtinPush},
+		"keys":      {Name: "keys", Fn: builtinKeys},
+		"values":    {Name: "values", Fn: builtinValues},
+		"has":       {Name: "has", Fn: builtinHas},
+	}
+}
+
+// len(xs) counts the characters of a string, the
+// pairs of a hash, or the elements of anything iterable
+func builtinLen(args ...object.Object) object.Object {
+	if err := checkArgs("len", args, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *object.String:
+		return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+	case *object.Array:
+		return &object.Integer{Value: int64(len(arg.Elements))}
+	case *object.Hash:
+		return &object.Integer{Value: int64(len(arg.Keys))}
+	case *object.Range:
+		if arg.Len() > math.MaxInt64 {
+			return newError(token.Position{}, "len: %s has more than %d elements", arg.Inspect(), int64(math.MaxInt64))
+		}
+		return &object.Integer{Value: int64(arg.Len())}
+	case object.Iterable:
+		n := int64(0)
+		it := arg.Iterate()
+		for x, ok := it.Next(); ok; x, ok = it.Next() {
+			if isError(x) {
+				return x
+			}
+			n++
+		}
+		return &object.Integer{Value: n}
+	default:
+		return newError(token.Position{}, "argument to len not supported, got %s", kindOf(arg))
+	}
+}
+
+// first(xs) gives the first element of xs
+func builtinFirst(args ...object.Object) object.Object {
+	if err := checkArgs("first", args, 1); err != nil {
+		return err
+	}
+	xs, ok := args[0].(object.Iterable)
+	if !ok {
+		return argumentError("first", "", args[0])
+	}
+
+	x, ok := xs.Iterate().Next()
+	if !ok {
+		return newError(token.Position{}, "first: empty %s", kindOf(xs))
+	}
+	return x
+}
+
+// rest(xs) gives every element of xs but the first
+func builtinRest(args ...object.Object) object.Object {
+	if err := checkArgs("rest", args, 1); err != nil {
+		return err
+	}
+	xs, ok := args[0].(object.Iterable)
+	if !ok {
+		return argumentError("rest", "", args[0])
+	}
+
+	x, ok := xs.Iterate().Next()
+	switch {
+	case !ok:
+		return newError(token.Position{}, "rest: empty %s", kindOf(xs))
+	case isError(x):
+		return x
+	}
+
+	if array, ok := xs.(*object.Array); ok {
+		return &object.Array{Elements: array.Elements[1:]}
+	}
+	return lazily(xs, func(next object.Iterator) object.IteratorFunc {
+		next.Next()
+		return next.Next
+	})
+}
+
+// push(x, xs) gives xs with x added on the end,
+// leaving xs itself as it was
+func builtinPush(args ...object.Object) object.Object {
+	if err := checkArgs("push", args, 2); err != nil {
+		return err
+	}
+	x := args[0]
+	xs, ok := args[1].(object.Iterable)
+	if !ok {
+		return argumentError("push", "last", args[1])
+	}
+
+	if array, ok := xs.(*object.Array); ok {
+		elements := make([]object.Object, len(array.Elements), len(array.Elements)+1)
+		copy(elements, array.Elements)
+		return &object.Array{Elements: append(elements, x)}
+	}
+	return lazily(xs, func(next object.Iterator) object.IteratorFunc {
+		pushed := false
+		return func() (object.Object, bool) {
+			if y, ok := next.Next(); ok {
+				return y, true
+			}
+			if pushed {
+				return nil, false
+			}
+			pushed = true
+			return x, true
+		}
+	})
+}
+
+// keys(h) gives the keys of h, in the
+// order they were first given in
+func builtinKeys(args ...object.Object) object.Object {
+	if err := checkArgs("keys", args, 1); err != nil {
+		return err
+	}
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return newError(token.Position{}, "argument to keys must be %s, got %s", object.HASH, kindOf(args[0]))
+	}
+
+	elements := make([]object.Object, 0, len(hash.Keys))
+	for _, key := range hash.Keys {
+		elements = append(elements, hash.Pairs[key].Key)
+	}
+	return &object.Array{Elements: elements}
+}
+
+// values(h) gives the values of h, in the
+// same order keys(h) gives the keys
+func builtinValues(args ...object.Object) object.Object {
+	if err := checkArgs("values", args, 1); err != nil {
+		return err
+	}
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return newError(token.Position{}, "argument to values must be %s, got %s", object.HASH, kindOf(args[0]))
+	}
+
+	elements := make([]object.Object, 0, len(hash.Keys))
+	for _, key := range hash.Keys {
+		elements = append(elements, hash.Pairs[key].Value)
+	}
+	return &object.Array{Elements: elements}
+}
+
+// has(k, h) reports whether h has the key k
+func builtinHas(args ...object.Object) object.Object {
+	if err := checkArgs("has", args, 2); err != nil {
+		return err
+	}
+	hash, ok := args[1].(*object.Hash)
+	if !ok {
+		return newError(token.Position{}, "last argument to has must be %s, got %s", object.HASH, kindOf(args[1]))
+	}
+	key, ok := args[0].(object.Hashable)
+	if !ok {
+		return newError(token.Position{}, "unusable as hash key: %s", kindOf(args[0]))
+	}
+
+	_, ok = hash.Get(key)
+	return mapBooleans(ok)
+}
+
+// map(f, xs) applies f to every element of xs
+func builtinMap(args ...object.Object) object.Object {
+	if err := checkArgs("map", args, 2); err != nil {
+		return err
+	}
+	f, xs, err := functionAndIterable("map", args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	return result(lazily(xs, func(next object.Iterator) object.IteratorFunc {
+		return func() (object.Object, bool) {
+			x, ok := next.Next()
+			if !ok || isError(x) {
+				return x, ok
+			}
+			return apply("function passed to map", f, []object.Object{x}), true
+		}
+	}), xs)
+}
+
+// filter(f, xs) keeps the elements of xs for which f is truthy
+func builtinFilter(args ...object.Object) object.Object {
+	if err := checkArgs("filter", args, 2); err != nil {
+		return err
+	}
+	f, xs, err := functionAndIterable("filter", args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	return result(lazily(xs, func(next object.Iterator) object.IteratorFunc {
+		return func() (object.Object, bool) {
+			for {
+				x, ok := next.Next()
+				if !ok || isError(x) {
+					return x, ok
+				}
+				keep := apply("function passed to filter", f, []object.Object{x})
+				if isError(keep) {
+					return keep, true
+				}
+				if isTruthy(keep) {
+					return x, true
+				}
+			}
+		}
+	}), xs)
+}
+
+// reduce(f, init, xs) folds xs from the left, calling
+// f with the value so far and the next element
+func builtinReduce(args ...object.Object) object.Object {
+	if err := checkArgs("reduce", args, 3); err != nil {
+		return err
+	}
+	f, xs, err := functionAndIterable("reduce", args[0], args[2])
+	if err != nil {
+		return err
+	}
+
+	acc := args[1]
+	it := xs.Iterate()
+	for x, ok := it.Next(); ok; x, ok = it.Next() {
+		if isError(x) {
+			return x
+		}
+		acc = apply("function passed to reduce", f, []object.Object{acc, x})
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+// take(n, xs) gives the first n elements of xs as an
+// array, and is an error if there aren't that many
+func builtinTake(args ...object.Object) object.Object {
+	if err := checkArgs("take", args, 2); err != nil {
+		return err
+	}
+	n, xs, err := countAndIterable("take", args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	if array, ok := xs.(*object.Array); ok {
+		if n > int64(len(array.Elements)) {
+			return newError(token.Position{}, "take: not enough elements, have %d, want %d", len(array.Elements), n)
+		}
+		return &object.Array{Elements: array.Elements[:n]}
+	}
+
+	elements := []object.Object{}
+	it := xs.Iterate()
+	for int64(len(elements)) < n {
+		x, ok := it.Next()
+		if !ok {
+			return newError(token.Position{}, "take: not enough elements, have %d, want %d", len(elements), n)
+		}
+		if isError(x) {
+			return x
+		}
+		elements = append(elements, x)
+	}
+	return &object.Array{Elements: elements}
+}
+
+// limit(n, xs) gives at most the first n elements of xs
+func builtinLimit(args ...object.Object) object.Object {
+	if err := checkArgs("limit", args, 2); err != nil {
+		return err
+	}
+	n, xs, err := countAndIterable("limit", args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	if array, ok := xs.(*object.Array); ok {
+		if n > int64(len(array.Elements)) {
+			return array
+		}
+		return &object.Array{Elements: array.Elements[:n]}
+	}
+
+	return lazily(xs, func(next object.Iterator) object.IteratorFunc {
+		seen := int64(0)
+		return func() (object.Object, bool) {
+			if seen >= n {
+				return nil, false
+			}
+			seen++
+			return next.Next()
+		}
+	})
+}
+
+// zip(xs, ys) pairs up the elements of xs and ys,
+// stopping at the end of the shorter one
+func builtinZip(args ...object.Object) object.Object {
+	if err := checkArgs("zip", args, 2); err != nil {
+		return err
+	}
+	xs, ok := args[0].(object.Iterable)
+	if !ok {
+		return argumentError("zip", "first", args[0])
+	}
+	ys, ok := args[1].(object.Iterable)
+	if !ok {
+		return argumentError("zip", "last", args[1])
+	}
+
+	return result(lazily(xs, func(next object.Iterator) object.IteratorFunc {
+		other := ys.Iterate()
+		return func() (object.Object, bool) {
+			x, ok := next.Next()
+			if !ok || isError(x) {
+				return x, ok
+			}
+			y, ok := other.Next()
+			if !ok || isError(y) {
+				return y, ok
+			}
+			return &object.Array{Elements: []object.Object{x, y}}, true
+		}
+	}), xs, ys)
+}
+
+// transform(f, xs) is map, except that f is
+// also given the index of each element
+func builtinTransform(args ...object.Object) object.Object {
+	if err := checkArgs("transform", args, 2); err != nil {
+		return err
+	}
+	f, xs, err := functionAndIterable("transform", args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	return result(lazily(xs, func(next object.Iterator) object.IteratorFunc {
+		i := int64(0)
+		return func() (object.Object, bool) {
+			x, ok := next.Next()
+			if !ok || isError(x) {
+				return x, ok
+			}
+			index := &object.Integer{Value: i}
+			i++
+			return apply("function passed to transform", f, []object.Object{x, index}), true
+		}
+	}), xs)
+}
+
+// lazily makes a sequence whose iterators are made by
+// wrapping an iterator of xs. Errors are passed along
+// as elements, for whoever consumes the sequence
+func lazily(xs object.Iterable, wrap func(object.Iterator) object.IteratorFunc) *object.Sequence {
+	return &object.Sequence{Iter: func() object.Iterator {
+		return wrap(xs.Iterate())
+	}}
+}
+
+// result gives back seq, worked out into an array
+// if all of the inputs were arrays
+func result(seq *object.Sequence, inputs ...object.Iterable) object.Object {
+	for _, input := range inputs {
+		if _, ok := input.(*object.Array); !ok {
+			return seq
+		}
+	}
+	return collect(seq)
+}
+
+// collect works out every element of xs into an
+// array, or gives back the first error
+func collect(xs object.Iterable) object.Object {
+	elements := []object.Object{}
+
+	it := xs.Iterate()
+	for x, ok := it.Next(); ok; x, ok = it.Next() {
+		if isError(x) {
+			return x
+		}
+		elements = append(elements, x)
+	}
+	return &object.Array{Elements: elements}
+}
+
+// functionAndIterable checks the first argument of
+// a builtin is callable, and that xs is iterable
+func functionAndIterable(name string, f, xs object.Object) (object.Object, object.Iterable, *object.Error) {
+	switch kindOf(f) {
+	case object.FUNCTION, object.BUILTIN:
+	default:
+		return nil, nil, newError(token.Position{}, "first argument to %s must be %s, got %s", name, object.FUNCTION, kindOf(f))
+	}
+
+	iterable, ok := xs.(object.Iterable)
+	if !ok {
+		return nil, nil, argumentError(name, "last", xs)
+	}
+	return f, iterable, nil
+}
+
+// countAndIterable checks n is a count and xs iterable
+func countAndIterable(name string, n, xs object.Object) (int64, object.Iterable, *object.Error) {
+	count, ok := n.(*object.Integer)
+	if !ok {
+		return 0, nil, newError(token.Position{}, "first argument to %s must be %s, got %s", name, object.INTEGER, kindOf(n))
+	}
+	if count.Value < 0 {
+		return 0, nil, newError(token.Position{}, "%s: negative count %d", name, count.Value)
+	}
+
+	iterable, ok := xs.(object.Iterable)
+	if !ok {
+		return 0, nil, argumentError(name, "last", xs)
+	}
+	return count.Value, iterable, nil
+}
+
+// argumentError reports a collection argument which
+// can't be iterated over. Which says which argument it
+// is, and may be left empty if there's only the one
+func argumentError(name, which string, got object.Object) *object.Error {
+	if which != "" {
+		which += " "
+	}
+	return newError(token.Position{}, "%sargument to %s must be iterable, got %s", which, name, kindOf(got))
+}
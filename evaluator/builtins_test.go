@@ -0,0 +1,471 @@
+package evaluator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/SCKelemen/oak/object"
+	"github.com/SCKelemen/oak/parser"
+	"github.com/SCKelemen/oak/scanner"
+)
+
+func TestBuiltins(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{"map(func(x) { x * 2 }, xs)", "[2, 4, 6]"},
+		{"map(func(x) { x > 1 }, xs)", "[false, true, true]"},
+		{"map(func(x) { x }, empty)", "[]"},
+		{"filter(func(x) { x != 2 }, xs)", "[1, 3]"},
+		{"filter(func(x) { false }, xs)", "[]"},
+		{"reduce(func(acc, x) { acc + x }, 0, xs)", "6"},
+		{"reduce(func(acc, x) { acc * 10 + x }, 0, xs)", "123"},
+		{"reduce(func(acc, x) { acc + x }, 7, empty)", "7"},
+		{"take(2, xs)", "[1, 2]"},
+		{"take(0, xs)", "[]"},
+		{"take(3, xs)", "[1, 2, 3]"},
+		{"limit(2, xs)", "[1, 2]"},
+		{"limit(10, xs)", "[1, 2, 3]"},
+		{"zip(xs, bs)", "[[1, true], [2, false]]"},
+		{"zip(empty, xs)", "[]"},
+		{"transform(func(x, i) { x * i }, xs)", "[0, 2, 6]"},
+		{"map(func(pair) { reduce(func(a, b) { b }, 0, pair) }, zip(xs, xs))", "[1, 2, 3]"},
+		{"let map = func(f, xs) { 42 }; map(1, 2)", "42"},
+		{"let double = func(x) { x * 2 }; map(double, filter(func(x) { x > 1 }, xs))", "[4, 6]"},
+		{"map", "builtin map"},
+	}
+
+	for _, tt := range tests {
+		val := testEvalWith(tt.input, collections())
+		if val == nil {
+			t.Errorf("%q: evaluated to nothing", tt.input)
+			continue
+		}
+		if val.Inspect() != tt.expecc {
+			t.Errorf("%q: expecc %s, received %s", tt.input, tt.expecc, val.Inspect())
+		}
+	}
+}
+
+func TestBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{"map(func(x) { x })", "not enough arguments in call to map, have 1, want 2"},
+		{"reduce(func(a, b) { a }, 0, xs, xs)", "too many arguments in call to reduce, have 4, want 3"},
+		{"map(1, xs)", "first argument to map must be FUNCTION, got INTEGER"},
+		{"filter(func(x) { x }, 5)", "last argument to filter must be iterable, got INTEGER"},
+		{"map(func(x, y) { x }, xs)", "not enough arguments in call to function passed to map, have 1, want 2"},
+		{"map(func(x) { x + true }, xs)", "type mismatch: INTEGER + BOOLEAN"},
+		{"take(4, xs)", "take: not enough elements, have 3, want 4"},
+		{"take(-1, xs)", "take: negative count -1"},
+		{"limit(true, xs)", "first argument to limit must be INTEGER, got BOOLEAN"},
+		{"zip(xs, 1)", "last argument to zip must be iterable, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		val := testEvalWith(tt.input, collections())
+		err, ok := val.(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned, received %T (%+v)", tt.input, val, val)
+			continue
+		}
+		if err.Message != tt.expecc {
+			t.Errorf("%q: wrong error message, expecc %q, received %q", tt.input, tt.expecc, err.Message)
+		}
+		if !err.Pos.IsValid() {
+			t.Errorf("%q: error has no position", tt.input)
+		}
+	}
+}
+
+// collections binds a few arrays to play with,
+// as there is no syntax to write them yet
+func collections() *object.Environment {
+	env := object.NewEnvironment()
+	env.Set("xs", &object.Array{Elements: []object.Object{
+		&object.Integer{Value: 1}, &object.Integer{Value: 2}, &object.Integer{Value: 3},
+	}})
+	env.Set("bs", &object.Array{Elements: []object.Object{TRUE, FALSE}})
+	env.Set("empty", &object.Array{})
+	return env
+}
+
+func testEvalWith(input string, env *object.Environment) object.Object {
+	p := parser.New(scanner.New(input))
+	return Eval(p.ParseProgram(), env)
+}
+
+func TestPipelines(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{"let isEven = func(x) { x / 2 * 2 == x }; xs |> filter(isEven)", "[2]"},
+		{"let double = func(x) { x * 2 }; xs |> map(double) |> filter(func(x) { x > 2 })", "[4, 6]"},
+		{"xs |> reduce(func(a, b) { a + b }, 0)", "6"},
+		{"xs |> limit(2) |> map(func(x) { x * 10 })", "[10, 20]"},
+		{"let inc = func(x) { x + 1 }; inc <| inc <| 1", "3"},
+		{"let inc = func(x) { x + 1 }; 1 |> inc |> inc", "3"},
+		{"map(func(x) { -x }) <| xs", "[-1, -2, -3]"},
+	}
+
+	for _, tt := range tests {
+		val := testEvalWith(tt.input, collections())
+		if val == nil {
+			t.Errorf("%q: evaluated to nothing", tt.input)
+			continue
+		}
+		if val.Inspect() != tt.expecc {
+			t.Errorf("%q: expecc %s, received %s", tt.input, tt.expecc, val.Inspect())
+		}
+	}
+
+	val := testEvalWith("xs |> take(5)", collections())
+	err, ok := val.(*object.Error)
+	if !ok || err.Message != "take: not enough elements, have 3, want 5" {
+		t.Fatalf("expecc take to fail, received %T (%+v)", val, val)
+	}
+	if err.Pos.Column != 1 {
+		t.Errorf("error should point at the start of the pipeline, received %s", err.Pos)
+	}
+}
+
+func TestRanges(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{"[1..5]", "[1..5]"},
+		{"[1..5] |> take(5)", "[1, 2, 3, 4, 5]"},
+		{"[1..<5] |> take(4)", "[1, 2, 3, 4]"},
+		{"[1, 3..9] |> take(5)", "[1, 3, 5, 7, 9]"},
+		{"[1, 3..<9] |> limit(10) |> reduce(func(a, b) { a + b }, 0)", "16"},
+		{"[5, 4..1] |> take(5)", "[5, 4, 3, 2, 1]"},
+		{"[5..1] |> limit(3) |> take(0)", "[]"},
+		{"[1..<1] |> reduce(func(a, b) { a + b }, 0)", "0"},
+		{"[1..1] |> reduce(func(a, b) { a + b }, 0)", "1"},
+		{"let n = 3; [n..n * 2] |> map(func(x) { x * x })", "[9, 16, 25, 36]"},
+		{"[1..20] |> filter(func(x) { x / 3 * 3 == x })", "[3, 6, 9, 12, 15, 18]"},
+		{"[1..20]", "[1..20]"},
+		{"[1..20] |> map(func(x) { x })", "[1, 2, 3, 4, 5, 6, 7, 8, 9, 10, ...]"},
+		{"zip(xs, [10..20]) |> limit(5)", "[[1, 10], [2, 11], [3, 12]]"},
+		{"[1..3] |> transform(func(x, i) { x * i })", "[0, 2, 6]"},
+		{"[1..3] |> map(func(x) { x / 0 })", "[ERROR: 1:27: division by zero]"},
+	}
+
+	for _, tt := range tests {
+		val := testEvalWith(tt.input, collections())
+		if val == nil {
+			t.Errorf("%q: evaluated to nothing", tt.input)
+			continue
+		}
+		if val.Inspect() != tt.expecc {
+			t.Errorf("%q: expecc %s, received %s", tt.input, tt.expecc, val.Inspect())
+		}
+	}
+}
+
+// TestLazyRanges checks huge ranges are never worked out
+// in full, which would take far too long to finish
+func TestLazyRanges(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{"[1..1_000_000_000_000] |> take(3)", "[1, 2, 3]"},
+		{"[1..1_000_000_000_000] |> map(func(x) { x * 2 }) |> filter(func(x) { x > 10 }) |> take(2)", "[12, 14]"},
+		{"[1..1_000_000_000_000] |> transform(func(x, i) { i }) |> limit(2) |> take(2)", "[0, 1]"},
+		{"zip([1..1_000_000_000_000], [0, -1..-1_000_000_000_000]) |> take(2)", "[[1, 0], [2, -1]]"},
+		{"[-9_223_372_036_854_775_807..9_223_372_036_854_775_807] |> take(2)", "[-9223372036854775807, -9223372036854775806]"},
+		{"[-9_223_372_036_854_775_807 - 1..9_223_372_036_854_775_807] |> take(2)", "[-9223372036854775808, -9223372036854775807]"},
+		{"[1..1_000_000_000_000] |> map(func(x) { x * 2 }) |> filter(func(x) { x > 10 })", "[12, 14, 16, 18, 20, 22, 24, 26, 28, 30, ...]"},
+	}
+
+	for _, tt := range tests {
+		val := testEvalWith(tt.input, collections())
+		if val == nil {
+			t.Errorf("%q: evaluated to nothing", tt.input)
+			continue
+		}
+		if val.Inspect() != tt.expecc {
+			t.Errorf("%q: expecc %s, received %s", tt.input, tt.expecc, val.Inspect())
+		}
+	}
+}
+
+func TestRangeLen(t *testing.T) {
+	tests := []struct {
+		r      object.Range
+		expecc uint64
+	}{
+		{object.Range{Start: 1, End: 10, Step: 1, Inclusive: true}, 10},
+		{object.Range{Start: 1, End: 10, Step: 1}, 9},
+		{object.Range{Start: 1, End: 10, Step: 3, Inclusive: true}, 4},
+		{object.Range{Start: 1, End: 10, Step: 3}, 3},
+		{object.Range{Start: 10, End: 1, Step: -1, Inclusive: true}, 10},
+		{object.Range{Start: 10, End: 1, Step: -4}, 3},
+		{object.Range{Start: 10, End: 1, Step: 1, Inclusive: true}, 0},
+		{object.Range{Start: 1, End: 10, Step: -1, Inclusive: true}, 0},
+		{object.Range{Start: 5, End: 5, Step: 1}, 0},
+		{object.Range{Start: 5, End: 5, Step: -1, Inclusive: true}, 1},
+		{object.Range{Start: math.MinInt64, End: math.MaxInt64, Step: 1}, math.MaxUint64},
+		// one more than a uint64 holds, so one short
+		{object.Range{Start: math.MinInt64, End: math.MaxInt64, Step: 1, Inclusive: true}, math.MaxUint64},
+		{object.Range{Start: math.MaxInt64, End: math.MinInt64, Step: -1, Inclusive: true}, math.MaxUint64},
+	}
+
+	for _, tt := range tests {
+		if n := tt.r.Len(); n != tt.expecc {
+			t.Errorf("%s: expecc %d elements, received %d", tt.r.Inspect(), tt.expecc, n)
+		}
+	}
+}
+
+func TestRangeErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{`[1.."ten"]`, "1:5: range bounds must be INTEGER, got STRING"},
+		{"[1.5..3]", "1:2: range bounds must be INTEGER, got FLOAT"},
+		{"[1, 1..3]", "1:5: range step must not be zero"},
+		{"[1..x]", "1:5: identifier not found: x"},
+		{"[1..3] |> take(4)", "1:1: take: not enough elements, have 3, want 4"},
+		{"len([1..3] |> map(func(x) { x / 0 }))", "1:31: division by zero"},
+	}
+
+	for _, tt := range tests {
+		val := testEvalWith(tt.input, collections())
+		err, ok := val.(*object.Error)
+		if !ok {
+			t.Errorf("%q: expecc an error, received %T (%+v)", tt.input, val, val)
+			continue
+		}
+		if err.Pos.String()+": "+err.Message != tt.expecc {
+			t.Errorf("%q: expecc %q, received %q", tt.input, tt.expecc, err.Pos.String()+": "+err.Message)
+		}
+	}
+}
+
+func TestArrays(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{"[]", "[]"},
+		{"[1, 2 * 2, 3 + 3]", "[1, 4, 6]"},
+		{"[1, true, [2]]", "[1, true, [2]]"},
+		{"[1, 2, 3][0]", "1"},
+		{"[1, 2, 3][2]", "3"},
+		{"let i = 1; [1, 2, 3][i + 1]", "3"},
+		{"let ys = [[1, 2], [3, 4]]; ys[1][0]", "3"},
+		{"[...xs, 4]", "[1, 2, 3, 4]"},
+		{"[0, ...xs, ...[1..3]]", "[0, 1, 2, 3, 1, 2, 3]"},
+		{"[...empty]", "[]"},
+		{"[10..20][3]", "13"},
+		{"[10, 8..0][5]", "0"},
+		{"[1..1_000_000_000_000][999_999_999_999]", "1000000000000"},
+		{"len(xs)", "3"},
+		{"len(empty)", "0"},
+		{"len([1..<10])", "9"},
+		{"len([1, 3..1_000_000_000_000])", "500000000000"},
+		{"first(xs)", "1"},
+		{"first([5, 4..1])", "5"},
+		{"len([1..3] |> filter(func(x) { x > 1 }))", "2"},
+		{"rest(xs)", "[2, 3]"},
+		{"rest([1])", "[]"},
+		{"rest([1..4])", "[2, 3, 4]"},
+		{"rest(rest(xs))", "[3]"},
+		{"push(4, xs)", "[1, 2, 3, 4]"},
+		{"push(1, empty)", "[1]"},
+		{"let ys = push(4, xs); xs", "[1, 2, 3]"},
+		{"xs |> push(4) |> rest", "[2, 3, 4]"},
+		{"[1..3] |> push(4)", "[1, 2, 3, 4]"},
+		{"let m = map(func(x) { x * 2 }, [1..3]); m[0]", "2"},
+		{"let m = [1..3] |> map(func(x) { x * 2 }); [m[2], len(m)]", "[6, 3]"},
+		{"([1..1_000_000_000_000] |> filter(func(x) { x > 10 }))[2]", "13"},
+		{"let sum = func(xs) { if (len(xs) == 0) { 0 } else { first(xs) + sum(rest(xs)) } }; sum([1..10])", "55"},
+	}
+
+	for _, tt := range tests {
+		val := testEvalWith(tt.input, collections())
+		if val == nil {
+			t.Errorf("%q: evaluated to nothing", tt.input)
+			continue
+		}
+		if val.Inspect() != tt.expecc {
+			t.Errorf("%q: expecc %s, received %s", tt.input, tt.expecc, val.Inspect())
+		}
+	}
+}
+
+func TestListEquality(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc bool
+	}{
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, 2] == [1, 2, 3]", false},
+		{"[] == empty", true},
+		{"[[1], [2, 3]] == [[1], [2, 3]]", true},
+		{"[1, 2.0] == [1.0, 2]", true},
+		{"xs == [1..3]", true},
+		{"[1..3] == [1, 2, 3]", true},
+		{"[1..3] == [1..<4]", true},
+		{"[1, 3..9] == [1, 3..10]", true},
+		{"[1, 3..9] == [1..9]", false},
+		{"[1..<1] == [5..<5]", true},
+		{"[1..1_000_000_000_000] == [1..1_000_000_000_000]", true},
+		{"([1..3] |> map(func(x) { x * 2 })) == [2, 4, 6]", true},
+		{"([1..3] |> map(func(x) { x * 2 })) == [1..3]", false},
+	}
+
+	for _, tt := range tests {
+		testBoolObj(t, testEvalWith(tt.input, collections()), tt.expecc)
+	}
+
+	errors := []struct {
+		input  string
+		expecc string
+	}{
+		{"[1] == {a: 1}", "1:5: type mismatch: ARRAY == RECORD"},
+		{"{a: 1} == {1: 1}", "1:8: type mismatch: RECORD == HASH"},
+		{"([1..3] |> map(func(x) { x / 0 })) == [1]", "1:28: division by zero"},
+	}
+
+	for _, tt := range errors {
+		val := testEvalWith(tt.input, collections())
+		err, ok := val.(*object.Error)
+		if !ok {
+			t.Errorf("%q: expecc an error, received %T (%+v)", tt.input, val, val)
+			continue
+		}
+		if err.Pos.String()+": "+err.Message != tt.expecc {
+			t.Errorf("%q: expecc %q, received %q", tt.input, tt.expecc, err.Pos.String()+": "+err.Message)
+		}
+	}
+}
+
+func TestArrayErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{"xs[3]", "1:4: index out of range [3] with length 3"},
+		{"xs[-1]", "1:4: index out of range [-1] with length 3"},
+		{"empty[0]", "1:7: index out of range [0] with length 0"},
+		{"[1..3][3]", "1:8: index out of range [3] with length 3"},
+		{"xs[true]", "1:4: index must be INTEGER, got BOOLEAN"},
+		{"let n = 1; n[0]", "1:13: cannot index n (INTEGER)"},
+		{"[1, x]", "1:5: identifier not found: x"},
+		{"[...1]", "1:2: cannot spread INTEGER into an array"},
+		{"first(empty)", "1:1: first: empty ARRAY"},
+		{"rest(empty)", "1:1: rest: empty ARRAY"},
+		{"first([1..<1])", "1:1: first: empty RANGE"},
+		{"first(1)", "1:1: argument to first must be iterable, got INTEGER"},
+		{"push(1, 2)", "1:1: last argument to push must be iterable, got INTEGER"},
+		{"len(1)", "1:1: argument to len not supported, got INTEGER"},
+		{"len([-9_223_372_036_854_775_807..9_223_372_036_854_775_807])", "1:1: len: [-9223372036854775807..9223372036854775807] has more than 9223372036854775807 elements"},
+		{"len([-9_223_372_036_854_775_807 - 1..9_223_372_036_854_775_807])", "1:1: len: [-9223372036854775808..9223372036854775807] has more than 9223372036854775807 elements"},
+		{"let m = [1..3] |> map(func(x) { x }); m[3]", "1:41: index out of range [3] with length 3"},
+		{"let m = [1..3] |> map(func(x) { x }); m[-1]", "1:41: index out of range [-1]"},
+		{"let m = [1..3] |> map(func(x) { x }); m[true]", "1:41: index must be INTEGER, got BOOLEAN"},
+		{"([1..3] |> map(func(x) { x / 0 }))[1]", "1:28: division by zero"},
+	}
+
+	for _, tt := range tests {
+		val := testEvalWith(tt.input, collections())
+		err, ok := val.(*object.Error)
+		if !ok {
+			t.Errorf("%q: expecc an error, received %T (%+v)", tt.input, val, val)
+			continue
+		}
+		if err.Pos.String()+": "+err.Message != tt.expecc {
+			t.Errorf("%q: expecc %q, received %q", tt.input, tt.expecc, err.Pos.String()+": "+err.Message)
+		}
+	}
+}
+
+func TestHashes(t *testing.T) {
+	codes := `let codes = {200: "OK", 201: "Created", 404: "Not Found"}; `
+
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{codes + "codes", "{200: OK, 201: Created, 404: Not Found}"},
+		{codes + "codes[404]", "Not Found"},
+		{codes + "let code = 201; codes[code]", "Created"},
+		{codes + "keys(codes)", "[200, 201, 404]"},
+		{codes + "values(codes)", "[OK, Created, Not Found]"},
+		{codes + "len(codes)", "3"},
+		{codes + "has(200, codes)", "true"},
+		{codes + "has(500, codes)", "false"},
+		{codes + `has("200", codes)`, "false"},
+		{codes + "keys(codes) |> filter(func(c) { c >= 400 })", "[404]"},
+		{`{"a": 1, "b": 2}["b"]`, "2"},
+		{`{"a": 1}["a" + ""]`, "1"},
+		{"{true: 1, false: 0}[1 > 2]", "0"},
+		{"{1: 2, 1: 3}", "{1: 3}"},
+		{"{2: 0, 1: 0, 2: 1}", "{2: 1, 1: 0}"},
+		{"let k = \"key\"; {(k): 1}", "{key: 1}"},
+		{"{1: {2: 3}}[1][2]", "3"},
+		{"{1: [1, 2]}[1][0]", "1"},
+		{`{1: "a", 2: "b"} == {2: "b", 1: "a"}`, "true"},
+		{`{1: "a"} == {1: "b"}`, "false"},
+		{`{1: "a"} == {2: "a"}`, "false"},
+		{`{1: "a"} != {1: "a", 2: "b"}`, "true"},
+		{`{"k": {v: [1]}} == {"k": {v: [1]}}`, "true"},
+		{"{:}", "{:}"},
+		{"keys({:})", "[]"},
+		{"values({:})", "[]"},
+		{"len({:})", "0"},
+		{"has(1, {:})", "false"},
+		{"{:} == {:}", "true"},
+	}
+
+	for _, tt := range tests {
+		val := testEvalWith(tt.input, collections())
+		if val == nil {
+			t.Errorf("%q: evaluated to nothing", tt.input)
+			continue
+		}
+		if val.Inspect() != tt.expecc {
+			t.Errorf("%q: expecc %s, received %s", tt.input, tt.expecc, val.Inspect())
+		}
+	}
+}
+
+func TestHashErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{"{1: 2}[3]", "1:8: key 3 not found"},
+		{`{1: 2}["1"]`, "1:8: key 1 not found"},
+		{"{1: 2}[1.5]", "1:8: unusable as hash key: FLOAT"},
+		{"{1.5: 2}", "1:2: unusable as hash key: FLOAT"},
+		{"{1: x}", "1:5: identifier not found: x"},
+		{"keys(xs)", "1:1: argument to keys must be HASH, got ARRAY"},
+		{"values(1)", "1:1: argument to values must be HASH, got INTEGER"},
+		{"has(1, xs)", "1:1: last argument to has must be HASH, got ARRAY"},
+		{"has(xs, {1: 2})", "1:1: unusable as hash key: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		val := testEvalWith(tt.input, collections())
+		err, ok := val.(*object.Error)
+		if !ok {
+			t.Errorf("%q: expecc an error, received %T (%+v)", tt.input, val, val)
+			continue
+		}
+		if err.Pos.String()+": "+err.Message != tt.expecc {
+			t.Errorf("%q: expecc %q, received %q", tt.input, tt.expecc, err.Pos.String()+": "+err.Message)
+		}
+	}
+}
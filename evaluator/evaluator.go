@@ -1,19 +1,57 @@
 package evaluator
 
 import (
+	"fmt"
+
 	"github.com/SCKelemen/oak/ast"
 	"github.com/SCKelemen/oak/object"
+	"github.com/SCKelemen/oak/token"
 )
 
-func Eval(node ast.Node) object.Object {
+func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	switch node := node.(type) {
 
 	case *ast.Program:
-		return evalStatements(node.Statements)
+		return unwrapReturnValue(evalStatements(node.Statements, env))
+
+	case *ast.BlockStatement:
+		return evalStatements(node.Statements, object.NewEnclosedEnvironment(env))
+
+	case *ast.ReturnStatement:
+		if node.ReturnValue == nil {
+			return &object.ReturnValue{Value: NULL}
+		}
+		val := Eval(node.ReturnValue, env)
+		if isError(val) {
+			return val
+		}
+		return &object.ReturnValue{Value: val}
+
+	case *ast.TypeDeclarationStatement:
+		// normally hoisted by evalStatements already
+		if t, ok := env.GetType(node.Name.Value); ok {
+			return t
+		}
+		if err := declareTypes([]ast.Statement{node}, env); err != nil {
+			return err
+		}
+		t, _ := env.GetType(node.Name.Value)
+		return t
+
+	case *ast.LetStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		env.Set(node.Name.Value, val)
+		return nil
 
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression)
+		return Eval(node.Expression, env)
+
+	case *ast.Identifier:
+		return evalIdentifier(node, env)
 
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
@@ -21,22 +59,898 @@ func Eval(node ast.Node) object.Object {
 	case *ast.Boolean:
 		return mapBooleans(node.Value)
 
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
+
+	case *ast.PrefixExpression:
+		right := Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
+		return evalPrefixExpression(node, right)
+
+	case *ast.InfixExpression:
+		left := Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		// && and || only look at the right if they must
+		switch {
+		case node.Operator == "&&" && left == FALSE:
+			return FALSE
+		case node.Operator == "||" && left == TRUE:
+			return TRUE
+		}
+		right := Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
+		return evalInfixExpression(node, left, right)
+
+	case *ast.IfExpression:
+		return evalIfExpression(node, env)
+
+	case *ast.SwitchExpression:
+		return evalSwitchExpression(node, env)
+
+	case *ast.RangeExpression:
+		return evalRangeExpression(node, env)
+
+	case *ast.ArrayLiteral:
+		return evalArrayLiteral(node, env)
+
+	case *ast.IndexExpression:
+		left := Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := Eval(node.Index, env)
+		if isError(index) {
+			return index
+		}
+		return evalIndexExpression(node, left, index)
+
+	case *ast.RecordLiteral:
+		return evalRecordLiteral(node, env)
+
+	case *ast.HashLiteral:
+		return evalHashLiteral(node, env)
+
+	case *ast.MemberExpression:
+		record := Eval(node.Object, env)
+		if isError(record) {
+			return record
+		}
+		return evalMemberExpression(node, record)
+
+	case *ast.SpreadExpression:
+		return newError(node.Pos(), "cannot use %s outside of a record", node)
+
+	case *ast.FunctionLiteral:
+		return &object.Function{Arguments: node.Arguments, Body: node.Body, Env: env}
+
+	case *ast.InvocationExpression:
+		function := Eval(node.Function, env)
+		if isError(function) {
+			return function
+		}
+		args := evalExpressions(node.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		return applyFunction(node, function, args)
+
 	default:
 		return nil
 	}
 
 }
 
-func evalStatements(stmts []ast.Statement) object.Object {
+// evalStatements evaluates stmts in order, stopping
+// at the first error or return, and gives back the
+// value of the last one. A return value is passed up
+// still wrapped, so that it keeps unwinding through
+// any blocks around it
+func evalStatements(stmts []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
+	if err := declareTypes(stmts, env); err != nil {
+		return err
+	}
+
 	for _, statement := range stmts {
-		result = Eval(statement)
+		result = Eval(statement, env)
+		if result != nil {
+			switch result.Kind() {
+			case object.ERROR, object.RETURN_VALUE:
+				return result
+			}
+		}
+	}
+
+	return result
+}
+
+// unwrapReturnValue stops a return from unwinding
+// any further, at the edge of a program or function
+func unwrapReturnValue(obj object.Object) object.Object {
+	if rv, ok := obj.(*object.ReturnValue); ok {
+		return rv.Value
 	}
+	return obj
+}
+
+// evalExpressions evaluates exprs left to right.
+// On an error it gives back just that error
+func evalExpressions(exprs []ast.Expression, env *object.Environment) []object.Object {
+	result := make([]object.Object, 0, len(exprs))
+
+	for _, expr := range exprs {
+		val := Eval(expr, env)
+		if isError(val) {
+			return []object.Object{val}
+		}
+		result = append(result, val)
+	}
+
+	return result
+}
 
+func applyFunction(node *ast.InvocationExpression, fn object.Object, args []object.Object) object.Object {
+	result := apply(node.Function.String(), fn, args)
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
 	return result
 }
 
+// apply calls fn, which is known as name to the
+// caller, with args. Errors about the call itself
+// come back without a position, for the caller to
+// fill in
+func apply(name string, fn object.Object, args []object.Object) object.Object {
+	switch fn := fn.(type) {
+	case *object.Function:
+		if err := checkArgs(name, args, len(fn.Arguments)); err != nil {
+			return err
+		}
+
+		env := object.NewEnclosedEnvironment(fn.Env)
+		for i, arg := range fn.Arguments {
+			env.Set(arg.Value, args[i])
+		}
+
+		result := unwrapReturnValue(evalStatements(fn.Body.Statements, env))
+		if result == nil {
+			return NULL
+		}
+		return result
+
+	case *object.Builtin:
+		return fn.Fn(args...)
+
+	default:
+		return newError(token.Position{}, "cannot call non-function %s (%s)", name, kindOf(fn))
+	}
+}
+
+// checkArgs makes sure a call to name has
+// exactly want arguments
+func checkArgs(name string, args []object.Object, want int) *object.Error {
+	switch {
+	case len(args) < want:
+		return newError(token.Position{}, "not enough arguments in call to %s, have %d, want %d", name, len(args), want)
+	case len(args) > want:
+		return newError(token.Position{}, "too many arguments in call to %s, have %d, want %d", name, len(args), want)
+	}
+	return nil
+}
+
+// evalArrayLiteral evaluates the elements in order,
+// splicing in those of anything spread into it
+func evalArrayLiteral(node *ast.ArrayLiteral, env *object.Environment) object.Object {
+	elements := []object.Object{}
+
+	for _, element := range node.Elements {
+		spread, ok := element.(*ast.SpreadExpression)
+		if !ok {
+			val := Eval(element, env)
+			if isError(val) {
+				return val
+			}
+			elements = append(elements, val)
+			continue
+		}
+
+		val := Eval(spread.Value, env)
+		if isError(val) {
+			return val
+		}
+		iterable, ok := val.(object.Iterable)
+		if !ok {
+			return newError(spread.Pos(), "cannot spread %s into an array", kindOf(val))
+		}
+		it := iterable.Iterate()
+		for x, ok := it.Next(); ok; x, ok = it.Next() {
+			if isError(x) {
+				return x
+			}
+			elements = append(elements, x)
+		}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// evalIndexExpression looks up xs[i], which must be
+// within the bounds of xs, or h[k], which h must have
+func evalIndexExpression(node *ast.IndexExpression, left, index object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		i, err := checkIndex(node, index, uint64(len(left.Elements)))
+		if err != nil {
+			return err
+		}
+		return left.Elements[i]
+
+	case *object.Range:
+		i, err := checkIndex(node, index, left.Len())
+		if err != nil {
+			return err
+		}
+		return left.At(i)
+
+	case *object.Sequence:
+		return indexSequence(node, left, index)
+
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError(node.Index.Pos(), "unusable as hash key: %s", kindOf(index))
+		}
+		val, ok := left.Get(key)
+		if !ok {
+			return newError(node.Index.Pos(), "key %s not found", index.Inspect())
+		}
+		return val
+
+	default:
+		return newError(node.Token.Pos(), "cannot index %s (%s)", node.Left, kindOf(left))
+	}
+}
+
+// checkIndex makes sure index is an integer
+// from 0 up to, but not including, length
+func checkIndex(node *ast.IndexExpression, index object.Object, length uint64) (uint64, *object.Error) {
+	i, ok := index.(*object.Integer)
+	if !ok {
+		return 0, newError(node.Index.Pos(), "index must be %s, got %s", object.INTEGER, kindOf(index))
+	}
+	if i.Value < 0 || uint64(i.Value) >= length {
+		return 0, newError(node.Index.Pos(), "index out of range [%d] with length %d", i.Value, length)
+	}
+	return uint64(i.Value), nil
+}
+
+// indexSequence walks seq up to index, as there is
+// no knowing how long a sequence is up front. Only
+// a negative index is out of range without a walk
+func indexSequence(node *ast.IndexExpression, seq *object.Sequence, index object.Object) object.Object {
+	i, ok := index.(*object.Integer)
+	if !ok {
+		return newError(node.Index.Pos(), "index must be %s, got %s", object.INTEGER, kindOf(index))
+	}
+	if i.Value < 0 {
+		return newError(node.Index.Pos(), "index out of range [%d]", i.Value)
+	}
+
+	n := int64(0)
+	it := seq.Iterate()
+	for obj, ok := it.Next(); ok; obj, ok = it.Next() {
+		if isError(obj) || n == i.Value {
+			return obj
+		}
+		n++
+	}
+	return newError(node.Index.Pos(), "index out of range [%d] with length %d", i.Value, n)
+}
+
+func evalRangeExpression(node *ast.RangeExpression, env *object.Environment) object.Object {
+	bounds := []ast.Expression{node.Start, node.Stop}
+	if node.Next != nil {
+		bounds = []ast.Expression{node.Start, node.Next, node.Stop}
+	}
+
+	values := make([]int64, len(bounds))
+	for i, bound := range bounds {
+		val := Eval(bound, env)
+		if isError(val) {
+			return val
+		}
+		integer, ok := val.(*object.Integer)
+		if !ok {
+			return newError(bound.Pos(), "range bounds must be %s, got %s", object.INTEGER, kindOf(val))
+		}
+		values[i] = integer.Value
+	}
+
+	r := &object.Range{Start: values[0], End: values[len(values)-1], Step: 1, Inclusive: !node.Exclusive}
+	if node.Next != nil {
+		r.Step = values[1] - values[0]
+		if r.Step == 0 {
+			return newError(node.Next.Pos(), "range step must not be zero")
+		}
+	}
+	return r
+}
+
+// evalRecordLiteral builds a record from its entries
+// in order, so a field overrides any of the same name
+// before it, spread or not, while keeping its place
+func evalRecordLiteral(node *ast.RecordLiteral, env *object.Environment) object.Object {
+	record := &object.Record{Names: []string{}, Fields: map[string]object.Object{}}
+	set := func(name string, val object.Object) {
+		if _, ok := record.Fields[name]; !ok {
+			record.Names = append(record.Names, name)
+		}
+		record.Fields[name] = val
+	}
+
+	for _, entry := range node.Entries {
+		switch entry := entry.(type) {
+		case *ast.RecordField:
+			val := Eval(entry.Value, env)
+			if isError(val) {
+				return val
+			}
+			set(entry.Name.Value, val)
+
+		case *ast.SpreadExpression:
+			val := Eval(entry.Value, env)
+			if isError(val) {
+				return val
+			}
+			other, ok := val.(*object.Record)
+			if !ok {
+				return newError(entry.Pos(), "cannot spread %s into a record", kindOf(val))
+			}
+			for _, name := range other.Names {
+				set(name, other.Fields[name])
+			}
+		}
+	}
+
+	return record
+}
+
+// evalHashLiteral evaluates each key and then its value,
+// in order. A repeated key overrides the value before
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for i, k := range node.Keys {
+		key := Eval(k, env)
+		if isError(key) {
+			return key
+		}
+		hashable, ok := key.(object.Hashable)
+		if !ok {
+			return newError(k.Pos(), "unusable as hash key: %s", kindOf(key))
+		}
+
+		val := Eval(node.Values[i], env)
+		if isError(val) {
+			return val
+		}
+		hash.Set(hashable, val)
+	}
+
+	return hash
+}
+
+func evalMemberExpression(node *ast.MemberExpression, obj object.Object) object.Object {
+	record, ok := obj.(*object.Record)
+	if !ok {
+		return newError(node.Token.Pos(), "%s.%s undefined (%s has no fields)", node.Object, node.Property, kindOf(obj))
+	}
+
+	field, ok := record.Get(node.Property.Value)
+	if !ok {
+		return newError(node.Property.Pos(), "%s.%s undefined (record has no field %s)", node.Object, node.Property, node.Property)
+	}
+	return field
+}
+
+func evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Object {
+	condition := Eval(node.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	var result object.Object
+	switch {
+	case isTruthy(condition):
+		result = Eval(node.Consequence, env)
+	case node.Alternative != nil:
+		result = Eval(node.Alternative, env)
+	}
+
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
+// evalSwitchExpression gives the result of the first arm
+// whose pattern matches the value, evaluated alongside
+// the names the pattern binds
+func evalSwitchExpression(node *ast.SwitchExpression, env *object.Environment) object.Object {
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	for _, arm := range node.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+		if match(arm.Pattern, value, armEnv) {
+			return Eval(arm.Result, armEnv)
+		}
+	}
+	return newError(node.Pos(), "no switch arm matched %s (%s)", value.Inspect(), kindOf(value))
+}
+
+// match reports whether value matches pattern, binding
+// the names in the pattern in env as it goes. A name
+// is a type to check the value belongs to if there's
+// one by that name, and otherwise binds the value
+func match(pattern ast.Expression, value object.Object, env *object.Environment) bool {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value == "_" {
+			return true
+		}
+		if t, ok := lookupType(pattern.Value, env); ok {
+			return t.Contains(value)
+		}
+		env.Set(pattern.Value, value)
+		return true
+
+	case *ast.RecordPattern:
+		record, ok := value.(*object.Record)
+		if !ok {
+			return false
+		}
+		for _, field := range pattern.Fields {
+			v, ok := record.Get(field.Name.Value)
+			if !ok || !match(field.Value, v, env) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		// the parser only allows literals otherwise,
+		// and those never need walking a sequence
+		eq, _ := equal(Eval(pattern, env), value)
+		return eq
+	}
+}
+
+// equal compares values the way == does. Numbers are
+// equal by value whatever their kind, and collections
+// are equal if what they hold is, with arrays, ranges
+// and sequences all being lists alike. Booleans and
+// null are singletons, so identity is equality for
+// them. The only error is from an element of a
+// sequence, which is worked out as it is compared
+func equal(a, b object.Object) (bool, *object.Error) {
+	switch {
+	case kindOf(a) == object.INTEGER && kindOf(b) == object.INTEGER:
+		return a.(*object.Integer).Value == b.(*object.Integer).Value, nil
+	case isNumber(a) && isNumber(b):
+		return toFloat(a) == toFloat(b), nil
+	case kindOf(a) == object.STRING && kindOf(b) == object.STRING:
+		return a.(*object.String).Value == b.(*object.String).Value, nil
+	case isList(a) && isList(b):
+		return equalLists(a.(object.Iterable), b.(object.Iterable))
+	case kindOf(a) == object.RECORD && kindOf(b) == object.RECORD:
+		return equalRecords(a.(*object.Record), b.(*object.Record))
+	case kindOf(a) == object.HASH && kindOf(b) == object.HASH:
+		return equalHashes(a.(*object.Hash), b.(*object.Hash))
+	default:
+		return a == b, nil
+	}
+}
+
+func isList(obj object.Object) bool {
+	switch kindOf(obj) {
+	case object.ARRAY, object.RANGE, object.SEQUENCE:
+		return true
+	}
+	return false
+}
+
+func equalLists(a, b object.Iterable) (bool, *object.Error) {
+	// ranges needn't be walked, they're equal if
+	// they're as long, and start and step the same
+	if ra, ok := a.(*object.Range); ok {
+		if rb, ok := b.(*object.Range); ok {
+			n := ra.Len()
+			switch {
+			case n != rb.Len():
+				return false, nil
+			case n == 0:
+				return true, nil
+			case n == 1:
+				return ra.Start == rb.Start, nil
+			default:
+				return ra.Start == rb.Start && ra.Step == rb.Step, nil
+			}
+		}
+	}
+
+	ia, ib := a.Iterate(), b.Iterate()
+	for {
+		x, okx := ia.Next()
+		y, oky := ib.Next()
+		if okx != oky {
+			return false, nil
+		}
+		if !okx {
+			return true, nil
+		}
+		for _, obj := range []object.Object{x, y} {
+			if isError(obj) {
+				return false, obj.(*object.Error)
+			}
+		}
+		if eq, err := equal(x, y); !eq || err != nil {
+			return eq, err
+		}
+	}
+}
+
+// equalRecords ignores the order of the fields,
+// which doesn't change the type of a record
+func equalRecords(a, b *object.Record) (bool, *object.Error) {
+	if len(a.Names) != len(b.Names) {
+		return false, nil
+	}
+	for _, name := range a.Names {
+		y, ok := b.Get(name)
+		if !ok {
+			return false, nil
+		}
+		if eq, err := equal(a.Fields[name], y); !eq || err != nil {
+			return eq, err
+		}
+	}
+	return true, nil
+}
+
+// equalHashes likewise ignores the order of the keys
+func equalHashes(a, b *object.Hash) (bool, *object.Error) {
+	if len(a.Keys) != len(b.Keys) {
+		return false, nil
+	}
+	for _, key := range a.Keys {
+		pair := a.Pairs[key]
+		y, ok := b.Get(pair.Key)
+		if !ok {
+			return false, nil
+		}
+		if eq, err := equal(pair.Value, y); !eq || err != nil {
+			return eq, err
+		}
+	}
+	return true, nil
+}
+
+func lookupType(name string, env *object.Environment) (object.Type, bool) {
+	if t, ok := env.GetType(name); ok {
+		return t, true
+	}
+	t, ok := builtinTypes[name]
+	return t, ok
+}
+
+func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+	if builtin, ok := builtins[node.Value]; ok {
+		return builtin
+	}
+	return newError(node.Pos(), "identifier not found: %s", node.Value)
+}
+
+func evalPrefixExpression(node *ast.PrefixExpression, right object.Object) object.Object {
+	switch node.Operator {
+	case "!":
+		return mapBooleans(!isTruthy(right))
+	case "-":
+		switch right := right.(type) {
+		case *object.Integer:
+			return &object.Integer{Value: -right.Value}
+		case *object.Float:
+			return &object.Float{Value: -right.Value}
+		default:
+			return newError(node.Token.Pos(), "unknown operator: -%s", kindOf(right))
+		}
+	default:
+		return newError(node.Token.Pos(), "unknown operator: %s%s", node.Operator, kindOf(right))
+	}
+}
+
+func evalInfixExpression(node *ast.InfixExpression, left, right object.Object) object.Object {
+	switch {
+	// the left side didn't decide it, so the right does,
+	// but only between booleans, as in the checker
+	case node.Operator == "&&" || node.Operator == "||":
+		if kindOf(left) == object.BOOLEAN && kindOf(right) == object.BOOLEAN {
+			return right
+		}
+		if kindOf(left) != kindOf(right) {
+			return newError(node.Token.Pos(), "type mismatch: %s %s %s", kindOf(left), node.Operator, kindOf(right))
+		}
+		return newError(node.Token.Pos(), "unknown operator: %s %s %s", kindOf(left), node.Operator, kindOf(right))
+
+	case kindOf(left) == object.INTEGER && kindOf(right) == object.INTEGER:
+		return evalIntegerInfixExpression(node, left.(*object.Integer), right.(*object.Integer))
+
+	// mixing integers and floats makes a float
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(node, toFloat(left), toFloat(right))
+
+	case kindOf(left) == object.STRING && kindOf(right) == object.STRING:
+		return evalStringInfixExpression(node, left.(*object.String), right.(*object.String))
+
+	case kindOf(left) != kindOf(right) && !(isList(left) && isList(right)):
+		return newError(node.Token.Pos(), "type mismatch: %s %s %s", kindOf(left), node.Operator, kindOf(right))
+
+	case node.Operator == "==" || node.Operator == "!=":
+		eq, err := equal(left, right)
+		if err != nil {
+			return err
+		}
+		return mapBooleans(eq == (node.Operator == "=="))
+
+	default:
+		return newError(node.Token.Pos(), "unknown operator: %s %s %s", kindOf(left), node.Operator, kindOf(right))
+	}
+}
+
+func evalIntegerInfixExpression(node *ast.InfixExpression, left, right *object.Integer) object.Object {
+	l, r := left.Value, right.Value
+
+	switch node.Operator {
+	case "+":
+		return &object.Integer{Value: l + r}
+	case "-":
+		return &object.Integer{Value: l - r}
+	case "*":
+		return &object.Integer{Value: l * r}
+	case "/":
+		if r == 0 {
+			return newError(node.Token.Pos(), "division by zero")
+		}
+		return &object.Integer{Value: l / r}
+	case "<":
+		return mapBooleans(l < r)
+	case ">":
+		return mapBooleans(l > r)
+	case "<=":
+		return mapBooleans(l <= r)
+	case ">=":
+		return mapBooleans(l >= r)
+	case "==":
+		return mapBooleans(l == r)
+	case "!=":
+		return mapBooleans(l != r)
+	default:
+		return newError(node.Token.Pos(), "unknown operator: %s %s %s", left.Kind(), node.Operator, right.Kind())
+	}
+}
+
+func evalFloatInfixExpression(node *ast.InfixExpression, l, r float64) object.Object {
+	switch node.Operator {
+	case "+":
+		return &object.Float{Value: l + r}
+	case "-":
+		return &object.Float{Value: l - r}
+	case "*":
+		return &object.Float{Value: l * r}
+	case "/":
+		// as with integers, rather than
+		// quietly giving back infinity
+		if r == 0 {
+			return newError(node.Token.Pos(), "division by zero")
+		}
+		return &object.Float{Value: l / r}
+	case "<":
+		return mapBooleans(l < r)
+	case ">":
+		return mapBooleans(l > r)
+	case "<=":
+		return mapBooleans(l <= r)
+	case ">=":
+		return mapBooleans(l >= r)
+	case "==":
+		return mapBooleans(l == r)
+	case "!=":
+		return mapBooleans(l != r)
+	default:
+		return newError(node.Token.Pos(), "unknown operator: %s %s %s", object.FLOAT, node.Operator, object.FLOAT)
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	switch kindOf(obj) {
+	case object.INTEGER, object.FLOAT:
+		return true
+	}
+	return false
+}
+
+func toFloat(obj object.Object) float64 {
+	if i, ok := obj.(*object.Integer); ok {
+		return float64(i.Value)
+	}
+	return obj.(*object.Float).Value
+}
+
+func evalStringInfixExpression(node *ast.InfixExpression, left, right *object.String) object.Object {
+	switch node.Operator {
+	case "+":
+		return &object.String{Value: left.Value + right.Value}
+	case "==":
+		return mapBooleans(left.Value == right.Value)
+	case "!=":
+		return mapBooleans(left.Value != right.Value)
+	default:
+		return newError(node.Token.Pos(), "unknown operator: %s %s %s", left.Kind(), node.Operator, right.Kind())
+	}
+}
+
+// isTruthy decides what counts as true where a
+// condition is wanted: false and null don't,
+// everything else does
+func isTruthy(obj object.Object) bool {
+	switch obj {
+	case FALSE, NULL, nil:
+		return false
+	default:
+		return true
+	}
+}
+
+// kindOf is obj.Kind(), treating statements
+// which produced nothing as null
+func kindOf(obj object.Object) object.ObjectKind {
+	if obj == nil {
+		return object.NULL
+	}
+	return obj.Kind()
+}
+
+// declareTypes evaluates every type declaration in
+// stmts up front, so that types may refer to those
+// declared further down, as StatusCode does in the
+// README. As in the checker, a type referring back to
+// itself other than through a property is an error,
+// reported for the first such declaration, since
+// nothing could ever be found to inhabit it
+func declareTypes(stmts []ast.Statement, env *object.Environment) *object.Error {
+	decls := []*ast.TypeDeclarationStatement{}
+	for _, statement := range stmts {
+		if decl, ok := statement.(*ast.TypeDeclarationStatement); ok {
+			env.SetType(decl.Name.Value, &object.NamedType{Name: decl.Name.Value})
+			decls = append(decls, decl)
+		}
+	}
+
+	for _, decl := range decls {
+		t, _ := env.GetType(decl.Name.Value)
+		named := t.(*object.NamedType)
+		named.Type = evalType(decl.Value, env)
+	}
+
+	// find every cycle before breaking any of them
+	var err *object.Error
+	recursive := []*object.NamedType{}
+	for _, decl := range decls {
+		t, _ := env.GetType(decl.Name.Value)
+		named := t.(*object.NamedType)
+		if isRecursive(named, named.Type, map[*object.NamedType]bool{}) {
+			if err == nil {
+				err = newError(decl.Name.Pos(), "invalid recursive type %s", named.Name)
+			}
+			recursive = append(recursive, named)
+		}
+	}
+	for _, named := range recursive {
+		named.Type = &object.Union{}
+	}
+
+	return err
+}
+
+// isRecursive reports whether t refers back to named
+// without going through a property first
+func isRecursive(named *object.NamedType, t object.Type, seen map[*object.NamedType]bool) bool {
+	switch t := t.(type) {
+	case *object.NamedType:
+		if t == named {
+			return true
+		}
+		if seen[t] {
+			return false
+		}
+		seen[t] = true
+		return isRecursive(named, t.Type, seen)
+	case *object.Union:
+		for _, v := range t.Variants {
+			if isRecursive(named, v, seen) {
+				return true
+			}
+		}
+	case *object.Intersection:
+		for _, member := range t.Types {
+			if isRecursive(named, member, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func evalType(expr ast.Expression, env *object.Environment) object.Type {
+	switch expr := expr.(type) {
+
+	case *ast.Identifier:
+		if t, ok := lookupType(expr.Value, env); ok {
+			return t
+		}
+		// leave unknown types unresolved, nothing inhabits them
+		return &object.NamedType{Name: expr.Value}
+
+	case *ast.LiteralType:
+		return &object.Literal{Value: Eval(expr.Value, env)}
+
+	case *ast.UnionType:
+		union := &object.Union{}
+		for _, v := range expr.Variants {
+			union.Variants = append(union.Variants, evalType(v.Type, env))
+		}
+		return union
+
+	case *ast.IntersectionType:
+		intersection := &object.Intersection{}
+		for _, t := range expr.Types {
+			intersection.Types = append(intersection.Types, evalType(t, env))
+		}
+		return intersection
+
+	case *ast.PropertyType:
+		return &object.Property{Name: expr.Name.Value, Type: evalType(expr.Type, env)}
+
+	default:
+		// an empty union, which nothing inhabits
+		return &object.Union{}
+	}
+}
+
+func newError(pos token.Position, format string, args ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, args...), Pos: pos}
+}
+
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Kind() == object.ERROR
+}
+
 func mapBooleans(val bool) *object.Boolean {
 	if val {
 		return TRUE
@@ -44,6 +958,13 @@ func mapBooleans(val bool) *object.Boolean {
 	return FALSE
 }
 
+var builtinTypes = map[string]object.Type{
+	"int":    &object.Primitive{Name: "int", Of: object.INTEGER},
+	"float":  &object.Primitive{Name: "float", Of: object.FLOAT},
+	"bool":   &object.Primitive{Name: "bool", Of: object.BOOLEAN},
+	"string": &object.Primitive{Name: "string", Of: object.STRING},
+}
+
 var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
@@ -15,6 +15,20 @@ func TestEvalIntegerExpr(t *testing.T) {
 	}{
 		{"5", 5},
 		{"10", 10},
+		{"-5", -5},
+		{"-10", -10},
+		{"5 + 5 + 5 + 5 - 10", 10},
+		{"2 * 2 * 2 * 2 * 2", 32},
+		{"-50 + 100 + -50", 0},
+		{"5 * 2 + 10", 20},
+		{"5 + 2 * 10", 25},
+		{"20 + 2 * -10", 0},
+		{"50 / 2 * 2 + 10", 60},
+		{"2 * (5 + 10)", 30},
+		{"3 * 3 * 3 + 10", 37},
+		{"3 * (3 * 3) + 10", 37},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 / 2", 3},
 	}
 
 	for _, tt := range tests {
@@ -29,7 +43,9 @@ func testEval(input string) object.Object {
 	p := parser.New(lxr)
 	program := p.ParseProgram()
 
-	return Eval(program)
+	env := object.NewEnvironment()
+
+	return Eval(program, env)
 }
 
 func TestEvalBooleanExpr(t *testing.T) {
@@ -39,6 +55,41 @@ func TestEvalBooleanExpr(t *testing.T) {
 	}{
 		{"true", true},
 		{"false", false},
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 < 1", false},
+		{"1 > 1", false},
+		{"1 == 1", true},
+		{"1 != 1", false},
+		{"1 == 2", false},
+		{"1 != 2", true},
+		{"true == true", true},
+		{"false == false", true},
+		{"true == false", false},
+		{"true != false", true},
+		{"false != true", true},
+		{"(1 < 2) == true", true},
+		{"(1 < 2) == false", false},
+		{"(1 > 2) == true", false},
+		{"(1 > 2) == false", true},
+		{"!true", false},
+		{"!false", true},
+		{"!5", false},
+		{"!!true", true},
+		{"!!false", false},
+		{"!!5", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"2.5 >= 2", true},
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
 	}
 
 	for _, tt := range tests {
@@ -76,3 +127,772 @@ func testBoolObj(t *testing.T, obj object.Object, expecc bool) bool {
 	}
 	return true
 }
+
+func TestEvalUnionType(t *testing.T) {
+	input := `
+	type Value =
+		| Number
+		| bool
+
+	type Number = | int
+	`
+
+	val := testEval(input)
+	typ, ok := val.(object.Type)
+	if !ok {
+		t.Fatalf("object is not a Type, received %T (%+v)", val, val)
+	}
+
+	if typ.Inspect() != "Number = | int" {
+		t.Errorf("type has unexpected Inspect(), received %q", typ.Inspect())
+	}
+
+	tests := []struct {
+		obj    object.Object
+		expecc bool
+	}{
+		{&object.Integer{Value: 5}, true},
+		{TRUE, false},
+		{NULL, false},
+	}
+
+	for _, tt := range tests {
+		if typ.Contains(tt.obj) != tt.expecc {
+			t.Errorf("Contains(%s) expecc %t", tt.obj.Inspect(), tt.expecc)
+		}
+	}
+}
+
+func TestUnionMembership(t *testing.T) {
+	lxr := scanner.New(`
+	type Value = | Number | bool
+	type Number = | int
+	type Nothing = | Unknown
+	`)
+	p := parser.New(lxr)
+	program := p.ParseProgram()
+
+	env := object.NewEnvironment()
+	declareTypes(program.Statements, env)
+
+	tests := []struct {
+		typ    string
+		obj    object.Object
+		expecc bool
+	}{
+		{"Value", &object.Integer{Value: 5}, true},
+		{"Value", TRUE, true},
+		{"Value", NULL, false},
+		{"Number", FALSE, false},
+		{"Nothing", &object.Integer{Value: 5}, false},
+	}
+
+	for _, tt := range tests {
+		typ, _ := env.GetType(tt.typ)
+		if typ.Contains(tt.obj) != tt.expecc {
+			t.Errorf("%s.Contains(%s) expecc %t", tt.typ, tt.obj.Inspect(), tt.expecc)
+		}
+	}
+}
+
+func TestLiteralTypes(t *testing.T) {
+	lxr := scanner.New(`
+	type SuccessCode =
+		| Ok
+		| Created
+		| NoContent
+
+	type Ok = 200
+	type Created = 201
+	type NoContent = 204
+
+	type Truthy = true
+	`)
+	p := parser.New(lxr)
+	program := p.ParseProgram()
+
+	env := object.NewEnvironment()
+	declareTypes(program.Statements, env)
+
+	tests := []struct {
+		typ    string
+		obj    object.Object
+		expecc bool
+	}{
+		{"Ok", &object.Integer{Value: 200}, true},
+		{"Ok", &object.Integer{Value: 201}, false},
+		{"SuccessCode", &object.Integer{Value: 201}, true},
+		{"SuccessCode", &object.Integer{Value: 204}, true},
+		{"SuccessCode", &object.Integer{Value: 404}, false},
+		{"SuccessCode", TRUE, false},
+		{"Truthy", TRUE, true},
+		{"Truthy", FALSE, false},
+		{"Truthy", &object.Integer{Value: 1}, false},
+	}
+
+	for _, tt := range tests {
+		typ, _ := env.GetType(tt.typ)
+		if typ.Contains(tt.obj) != tt.expecc {
+			t.Errorf("%s.Contains(%s) expecc %t", tt.typ, tt.obj.Inspect(), tt.expecc)
+		}
+	}
+}
+
+func TestRecursiveTypes(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{"type A = A; 1", "ERROR: 1:6: invalid recursive type A"},
+		{"type A = B; type B = A; switch (1) { A => 1, _ => 2 }", "ERROR: 1:6: invalid recursive type A"},
+		{"type k = k | 4; switch (2) { k => 1, _ => 2 }", "ERROR: 1:6: invalid recursive type k"},
+		{"let f = func() { type T = | int | T; 1 }; f()", "ERROR: 1:23: invalid recursive type T"},
+		{"type List = head: int & tail: Tail; type Tail = | List | Nil; type Nil = 0; switch ({head: 1, tail: {head: 2, tail: 0}}) { List => 1, _ => 2 }", "1"},
+	}
+
+	for _, tt := range tests {
+		val := testEval(tt.input)
+		if val == nil || val.Inspect() != tt.expecc {
+			t.Errorf("%q: expecc %s, received %v", tt.input, tt.expecc, val)
+		}
+	}
+
+	// types declared one at a time, as in the REPL
+	env := object.NewEnvironment()
+	for _, input := range []string{"type A = B", "type B = A", "type A = A"} {
+		Eval(parser.New(scanner.New(input)).ParseProgram(), env)
+	}
+	a, _ := env.GetType("A")
+	if a.Contains(&object.Integer{Value: 1}) {
+		t.Errorf("recursive type A expecc to contain nothing")
+	}
+}
+
+func TestLetStatements(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc int64
+	}{
+		{"let a = 5; a;", 5},
+		{"let a = 5; let b = a; b;", 5},
+		{"let a = 5; let b = 10; a;", 5},
+		{"let a = 5; let a = 7; a;", 7},
+	}
+
+	for _, tt := range tests {
+		testIntegerObj(t, testEval(tt.input), tt.expecc)
+	}
+}
+
+func TestIdentifierNotFound(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{"foobar", "identifier not found: foobar"},
+		{"let a = 5;\nlet b = c;\nb", "identifier not found: c"},
+	}
+
+	for _, tt := range tests {
+		val := testEval(tt.input)
+
+		err, ok := val.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, received %T (%+v)", val, val)
+			continue
+		}
+		if err.Message != tt.expecc {
+			t.Errorf("wrong error message, expecc %q, received %q", tt.expecc, err.Message)
+		}
+	}
+
+	err, ok := testEval("let a = 5;\nlet b = c;\nb").(*object.Error)
+	if ok && (err.Pos.Line != 2 || err.Pos.Column != 9) {
+		t.Errorf("error at wrong position, expecc 2:9, received %s", err.Pos)
+	}
+}
+
+func TestEnvironmentScopes(t *testing.T) {
+	outer := object.NewEnvironment()
+	outer.Set("a", &object.Integer{Value: 1})
+	outer.Set("b", &object.Integer{Value: 2})
+
+	inner := object.NewEnclosedEnvironment(outer)
+	inner.Set("a", &object.Integer{Value: 3})
+
+	val, _ := inner.Get("a")
+	testIntegerObj(t, val, 3)
+	val, _ = inner.Get("b")
+	testIntegerObj(t, val, 2)
+	val, _ = outer.Get("a")
+	testIntegerObj(t, val, 1)
+
+	if _, ok := outer.Get("c"); ok {
+		t.Errorf("outer environment found a binding for c")
+	}
+}
+
+func TestLetAndTypesAreSeparate(t *testing.T) {
+	env := object.NewEnvironment()
+	lxr := scanner.New("type x = int\nlet x = 5\nx")
+	p := parser.New(lxr)
+
+	testIntegerObj(t, Eval(p.ParseProgram(), env), 5)
+
+	typ, ok := env.GetType("x")
+	if !ok || !typ.Contains(&object.Integer{Value: 1}) {
+		t.Errorf("type x was lost, received %v", typ)
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+		line   int
+		column int
+	}{
+		{"5 + true;", "type mismatch: INTEGER + BOOLEAN", 1, 3},
+		{"5 + true; 5;", "type mismatch: INTEGER + BOOLEAN", 1, 3},
+		{"-true", "unknown operator: -BOOLEAN", 1, 1},
+		{"true + false;", "unknown operator: BOOLEAN + BOOLEAN", 1, 6},
+		{"5;\n  true * false; 5", "unknown operator: BOOLEAN * BOOLEAN", 2, 8},
+		{"true < false", "unknown operator: BOOLEAN < BOOLEAN", 1, 6},
+		{"1 == true", "type mismatch: INTEGER == BOOLEAN", 1, 3},
+		{"-(true + 1) + 2", "type mismatch: BOOLEAN + INTEGER", 1, 8},
+		{"10 / (5 - 5)", "division by zero", 1, 4},
+	}
+
+	for _, tt := range tests {
+		val := testEval(tt.input)
+
+		err, ok := val.(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned, received %T (%+v)", tt.input, val, val)
+			continue
+		}
+		if err.Message != tt.expecc {
+			t.Errorf("%q: wrong error message, expecc %q, received %q", tt.input, tt.expecc, err.Message)
+		}
+		if err.Pos.Line != tt.line || err.Pos.Column != tt.column {
+			t.Errorf("%q: error at wrong position, expecc %d:%d, received %s", tt.input, tt.line, tt.column, err.Pos)
+		}
+	}
+}
+
+func TestIfElseExpressions(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc interface{}
+	}{
+		{"if (true) { 10 }", 10},
+		{"if (false) { 10 }", nil},
+		{"if (1) { 10 }", 10},
+		{"if (1 < 2) { 10 }", 10},
+		{"if (1 > 2) { 10 }", nil},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (0) { 10 } else { 20 }", 10},
+		{"if (!true) { 10 }", nil},
+		{"if (true) { }", nil},
+	}
+
+	for _, tt := range tests {
+		val := testEval(tt.input)
+		if integer, ok := tt.expecc.(int); ok {
+			testIntegerObj(t, val, int64(integer))
+		} else if val != NULL {
+			t.Errorf("%q: object is not NULL, received %T (%+v)", tt.input, val, val)
+		}
+	}
+}
+
+func TestReturnStatements(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc int64
+	}{
+		{"return 10;", 10},
+		{"return 10; 9;", 10},
+		{"return 2 * 5; 9;", 10},
+		{"9; return 2 * 5; 9;", 10},
+		{"return 10", 10},
+		{"if (10 > 1) { return 10 }", 10},
+		{`
+if (10 > 1) {
+	if (10 > 1) {
+		return 10;
+	}
+
+	return 1;
+}`, 10},
+	}
+
+	for _, tt := range tests {
+		val := testEval(tt.input)
+		testIntegerObj(t, val, tt.expecc)
+	}
+
+	if val := testEval("return;"); val != NULL {
+		t.Errorf("bare return gave %T (%+v), expecc NULL", val, val)
+	}
+}
+
+func TestBlockScope(t *testing.T) {
+	val := testEval("let x = 1; if (true) { let x = 2; x }")
+	testIntegerObj(t, val, 2)
+
+	val = testEval("let x = 1; if (true) { let x = 2; }; x")
+	testIntegerObj(t, val, 1)
+
+	val = testEval("if (true) { let y = 2; }; y")
+	if err, ok := val.(*object.Error); !ok || err.Message != "identifier not found: y" {
+		t.Errorf("block binding leaked, received %T (%+v)", val, val)
+	}
+}
+
+func TestConditionErrors(t *testing.T) {
+	val := testEval("if (1 + true) { 10 }")
+	if err, ok := val.(*object.Error); !ok || err.Message != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("condition error was not propagated, received %T (%+v)", val, val)
+	}
+
+	val = testEval("if (true) { if (true) { true + 1; 2 } return 10 }")
+	if err, ok := val.(*object.Error); !ok || err.Message != "type mismatch: BOOLEAN + INTEGER" {
+		t.Errorf("nested block error was not propagated, received %T (%+v)", val, val)
+	}
+}
+
+func TestFunctionObject(t *testing.T) {
+	val := testEval("func(x) { x + 2; };")
+
+	fn, ok := val.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not a Function, received %T (%+v)", val, val)
+	}
+	if len(fn.Arguments) != 1 || fn.Arguments[0].String() != "x" {
+		t.Fatalf("function has wrong arguments, received %v", fn.Arguments)
+	}
+	if fn.Body.String() != "(x + 2)" {
+		t.Fatalf("function has wrong body, expecc %q, received %q", "(x + 2)", fn.Body.String())
+	}
+}
+
+func TestFunctionInvocation(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc int64
+	}{
+		{"let identity = func(x) { x; }; identity(5);", 5},
+		{"let identity = func(x) { return x; }; identity(5);", 5},
+		{"let double = func(x) { x * 2; }; double(5);", 10},
+		{"let add = func(x, y) { x + y; }; add(5, 5);", 10},
+		{"let add = func(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+		{"func(x) { x; }(5)", 5},
+		{"let early = func(x) { if (x > 1) { return 1; } return 2; }; early(5) + early(0)", 3},
+		{"let fact = func(n) { if (n < 2) { return 1 } n * fact(n - 1) }; fact(5)", 120},
+	}
+
+	for _, tt := range tests {
+		testIntegerObj(t, testEval(tt.input), tt.expecc)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc int64
+	}{
+		{`
+let newAdder = func(x) {
+	func(y) { x + y };
+};
+
+let addTwo = newAdder(2);
+addTwo(2);`, 4},
+		{`
+let x = 10;
+let f = func() { x };
+let g = func(x) { f() };
+g(1)`, 10},
+		{`
+let add = func(a, b) { a + b };
+let apply = func(f, a, b) { f(a, b) };
+apply(add, 2, 3)`, 5},
+		{`
+let twice = func(f) { func(x) { f(f(x)) } };
+let inc = func(x) { x + 1 };
+twice(twice(inc))(0)`, 4},
+	}
+
+	for _, tt := range tests {
+		testIntegerObj(t, testEval(tt.input), tt.expecc)
+	}
+}
+
+func TestInvocationErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{"let f = func(x, y) { x }; f(1)", "not enough arguments in call to f, have 1, want 2"},
+		{"let f = func(x) { x }; f(1, 2)", "too many arguments in call to f, have 2, want 1"},
+		{"let x = 5; x(1)", "cannot call non-function x (INTEGER)"},
+		{"let f = func(x) { x }; f(1 + true)", "type mismatch: INTEGER + BOOLEAN"},
+		{"let f = func() { y }; f()", "identifier not found: y"},
+	}
+
+	for _, tt := range tests {
+		val := testEval(tt.input)
+		err, ok := val.(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned, received %T (%+v)", tt.input, val, val)
+			continue
+		}
+		if err.Message != tt.expecc {
+			t.Errorf("%q: wrong error message, expecc %q, received %q", tt.input, tt.expecc, err.Message)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc interface{}
+	}{
+		{`"Hello World!"`, "Hello World!"},
+		{`"Hello" + " " + "World!"`, "Hello World!"},
+		{`let greet = func(name) { "hi, " + name }; greet("oak")`, "hi, oak"},
+		{`"oak" == "oak"`, true},
+		{`"oak" == "elm"`, false},
+		{`"oak" != "elm"`, true},
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("🌳 oak")`, 5},
+		{`len("hello" + "world")`, 10},
+	}
+
+	for _, tt := range tests {
+		val := testEval(tt.input)
+		switch expecc := tt.expecc.(type) {
+		case string:
+			str, ok := val.(*object.String)
+			if !ok {
+				t.Errorf("%s: object is not a String, received %T (%+v)", tt.input, val, val)
+				continue
+			}
+			if str.Value != expecc {
+				t.Errorf("%s: expecc %q, received %q", tt.input, expecc, str.Value)
+			}
+		case bool:
+			testBoolObj(t, val, expecc)
+		case int:
+			testIntegerObj(t, val, int64(expecc))
+		}
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{`"Hello" - "World"`, "unknown operator: STRING - STRING"},
+		{`"one" + 1`, "type mismatch: STRING + INTEGER"},
+		{`len(1)`, "argument to len not supported, got INTEGER"},
+		{`len("one", "two")`, "too many arguments in call to len, have 2, want 1"},
+	}
+
+	for _, tt := range tests {
+		val := testEval(tt.input)
+		err, ok := val.(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned, received %T (%+v)", tt.input, val, val)
+			continue
+		}
+		if err.Message != tt.expecc {
+			t.Errorf("%s: wrong error message, expecc %q, received %q", tt.input, tt.expecc, err.Message)
+		}
+	}
+}
+
+func TestFloats(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc interface{}
+	}{
+		{"1.5", 1.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3.0},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2.0},
+		{"7 / 2.0", 3.5},
+		{"1e3 - 1", 999.0},
+		{"0xFF + 0b1 + 0o10 + 1_000", int64(1264)},
+		{"1.5 > 1", true},
+		{"1 < 0.5", false},
+		{"2 == 2.0", true},
+		{"0.1 + 0.2 != 0.3", true},
+	}
+
+	for _, tt := range tests {
+		val := testEval(tt.input)
+		switch expecc := tt.expecc.(type) {
+		case float64:
+			f, ok := val.(*object.Float)
+			if !ok {
+				t.Errorf("%s: object is not a Float, received %T (%+v)", tt.input, val, val)
+				continue
+			}
+			if f.Value != expecc {
+				t.Errorf("%s: expecc %g, received %g", tt.input, expecc, f.Value)
+			}
+		case int64:
+			testIntegerObj(t, val, expecc)
+		case bool:
+			testBoolObj(t, val, expecc)
+		}
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value  float64
+		expecc string
+	}{
+		{1.5, "1.5"},
+		{2, "2.0"},
+		{-3, "-3.0"},
+		{1e21, "1e+21"},
+		{0.000001, "1e-06"},
+	}
+
+	for _, tt := range tests {
+		f := &object.Float{Value: tt.value}
+		if f.Inspect() != tt.expecc {
+			t.Errorf("Inspect() of %g, expecc %q, received %q", tt.value, tt.expecc, f.Inspect())
+		}
+	}
+
+	val := testEval("1.5 / 0")
+	if err, ok := val.(*object.Error); !ok || err.Message != "division by zero" {
+		t.Errorf("expecc division by zero, received %T (%+v)", val, val)
+	}
+	val = testEval("1.5 + true")
+	if err, ok := val.(*object.Error); !ok || err.Message != "type mismatch: FLOAT + BOOLEAN" {
+		t.Errorf("expecc type mismatch, received %T (%+v)", val, val)
+	}
+}
+
+func TestShortCircuit(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc bool
+	}{
+		// the right side would be an error if it ran
+		{"false && undefined", false},
+		{"true || undefined", true},
+		{"false && 1 / 0 == 1", false},
+		{"1 > 2 && (true + 1)", false},
+	}
+
+	for _, tt := range tests {
+		testBoolObj(t, testEval(tt.input), tt.expecc)
+	}
+
+	val := testEval("true && undefined")
+	if err, ok := val.(*object.Error); !ok || err.Message != "identifier not found: undefined" {
+		t.Errorf("right side of && wasn't evaluated, received %T (%+v)", val, val)
+	}
+
+	// both sides must be booleans, whatever they are
+	errors := []struct {
+		input  string
+		expecc string
+	}{
+		{"1 && 2", "ERROR: 1:3: unknown operator: INTEGER && INTEGER"},
+		{"1.5 || 2.5", "ERROR: 1:5: unknown operator: FLOAT || FLOAT"},
+		{`"a" && "b"`, "ERROR: 1:5: unknown operator: STRING && STRING"},
+		{`"a" || "b"`, "ERROR: 1:5: unknown operator: STRING || STRING"},
+		{"true && 5", "ERROR: 1:6: type mismatch: BOOLEAN && INTEGER"},
+		{"false || \"b\"", "ERROR: 1:7: type mismatch: BOOLEAN || STRING"},
+		{"5 && true", "ERROR: 1:3: type mismatch: INTEGER && BOOLEAN"},
+		{"0 || true", "ERROR: 1:3: type mismatch: INTEGER || BOOLEAN"},
+		{"{} && false", "ERROR: 1:4: type mismatch: RECORD && BOOLEAN"},
+	}
+
+	for _, tt := range errors {
+		val := testEval(tt.input)
+		if val == nil || val.Inspect() != tt.expecc {
+			t.Errorf("%q: expecc %s, received %v", tt.input, tt.expecc, val)
+		}
+	}
+}
+
+func TestRecords(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{"{}", "{}"},
+		{"{a: 1, b: true}", "{a: 1, b: true}"},
+		{"{a: 1 + 2, b: {c: 3},}", "{a: 3, b: {c: 3}}"},
+		{"{a: 1, b: 2}.b", "2"},
+		{"let r = {a: 1, b: {c: 3}}; r.b.c", "3"},
+		{"let r = {a: 1, b: 2}; {...r}", "{a: 1, b: 2}"},
+		{"let r = {a: 1, b: 2}; {...r, b: 3, c: 4}", "{a: 1, b: 3, c: 4}"},
+		{"let r = {a: 1, b: 2}; {c: 0, ...r, a: 5}", "{c: 0, a: 5, b: 2}"},
+		{"let r = {a: 1, b: 2}; let s = {...r, a: 5}; r.a", "1"},
+		{"let r = {a: 1}; let s = {b: 2}; {...r, ...s}", "{a: 1, b: 2}"},
+		{"let p = {x: 1, y: 2}; let f = func(p) { p.x + p.y }; f(p)", "3"},
+		{"let f = func() { {n: 1} }; f().n", "1"},
+		{"let r = {a: 1, a: 2}; r.a", "2"},
+		{"{a: 1, b: [2]} == {a: 1, b: [2]}", "true"},
+		{"{a: 1, b: 2} == {b: 2, a: 1}", "true"},
+		{"{a: 1} == {a: 2}", "false"},
+		{"{a: 1} == {b: 1}", "false"},
+		{"{a: 1} != {a: 1, b: 2}", "true"},
+		{"{} == {}", "true"},
+		{"let r = {a: {b: 1}}; {...r} == r", "true"},
+	}
+
+	for _, tt := range tests {
+		val := testEval(tt.input)
+		if val == nil {
+			t.Errorf("%q: evaluated to nothing", tt.input)
+			continue
+		}
+		if val.Inspect() != tt.expecc {
+			t.Errorf("%q: expecc %s, received %s", tt.input, tt.expecc, val.Inspect())
+		}
+	}
+}
+
+func TestRecordErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{"let r = {a: 1}; r.b", "ERROR: 1:19: r.b undefined (record has no field b)"},
+		{"let n = 1; n.b", "ERROR: 1:13: n.b undefined (INTEGER has no fields)"},
+		{"{...1}", "ERROR: 1:2: cannot spread INTEGER into a record"},
+		{"{a: x}", "ERROR: 1:5: identifier not found: x"},
+		{"let r = {a: 1}; ...r", "ERROR: 1:17: cannot use ...r outside of a record"},
+	}
+
+	for _, tt := range tests {
+		val := testEval(tt.input)
+		if val == nil || val.Inspect() != tt.expecc {
+			t.Errorf("%q: expecc %s, received %v", tt.input, tt.expecc, val)
+		}
+	}
+}
+
+func TestRecordMembership(t *testing.T) {
+	lxr := scanner.New(`
+	type Point = x: int & y: int
+	Lexer: type
+		.input: Point
+		.current: bool
+	`)
+	p := parser.New(lxr)
+	program := p.ParseProgram()
+
+	env := object.NewEnvironment()
+	declareTypes(program.Statements, env)
+
+	tests := []struct {
+		typ    string
+		input  string
+		expecc bool
+	}{
+		{"Point", "{x: 1, y: 2}", true},
+		{"Point", "{y: 2, x: 1, z: 3}", true},
+		{"Point", "{x: 1}", false},
+		{"Point", "{x: 1, y: true}", false},
+		{"Point", "1", false},
+		{"Lexer", "{input: {x: 1, y: 2}, current: true}", true},
+		{"Lexer", "{input: {x: 1}, current: true}", false},
+	}
+
+	for _, tt := range tests {
+		typ, _ := env.GetType(tt.typ)
+		obj := testEval(tt.input)
+		if typ.Contains(obj) != tt.expecc {
+			t.Errorf("%s.Contains(%s) expecc %t", tt.typ, obj.Inspect(), tt.expecc)
+		}
+	}
+}
+
+func TestSwitchExpressions(t *testing.T) {
+	types := `
+	type StatusCode = | SuccessCode | ClientErrorCode
+	type SuccessCode = | Ok | Created
+	type ClientErrorCode = | NotFound
+	type Ok = 200
+	type Created = 201
+	type NotFound = 404
+	type Point = x: int & y: int
+	`
+
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{"switch (1) { 1 => \"one\", 2 => \"two\" }", "one"},
+		{"switch (2) { 1 => \"one\", 2 => \"two\" }", "two"},
+		{"switch (3) { 1 => \"one\", _ => \"many\" }", "many"},
+		{"switch (-1) { -1 => true, _ => false }", "true"},
+		{"switch (1.5) { 1.5 => 1, _ => 0 }", "1"},
+		{"switch (2) { 2.0 => 1, _ => 0 }", "1"},
+		{"switch (1.0) { 1 => \"int\", _ => \"other\" }", "int"},
+		{"switch (1.5) { 1 => \"int\", _ => \"other\" }", "other"},
+		{"switch (\"b\") { \"a\" => 1, \"b\" => 2, _ => 0 }", "2"},
+		{"switch (1 > 2) { true => \"yes\", false => \"no\" }", "no"},
+		{"switch (5) { n => n * 2 }", "10"},
+		{"let n = 1; switch (5) { n => n }; n", "1"},
+		{"switch (true) { int => \"int\", bool => \"bool\" }", "bool"},
+		{"switch (\"s\") { int => 1, string => 2 }", "2"},
+		{types + "switch (201) { Ok => \"ok\", Created => \"created\", _ => \"?\" }", "created"},
+		{types + "switch (404) { SuccessCode => \"success\", ClientErrorCode => \"client error\" }", "client error"},
+		{types + "switch (200) { StatusCode => \"status\", _ => \"other\" }", "status"},
+		{types + "switch (500) { StatusCode => \"status\", _ => \"other\" }", "other"},
+		{"switch ({code: 200, body: \"hi\"}) { {code: 404} => \"missing\", {code: 200, body} => body }", "hi"},
+		{"switch ({code: 500}) { {code: 200} => 0, {code: c} => c }", "500"},
+		{"switch ({a: 1}) { {a: 1, b: _} => \"both\", {a: _} => \"a\" }", "a"},
+		{"switch ({p: {x: 1, y: 2}}) { {p: {x: 1, y}} => y }", "2"},
+		{"switch (1) { {} => \"record\", _ => \"other\" }", "other"},
+		{"switch ({}) { {} => \"record\", _ => \"other\" }", "record"},
+		{types + "switch ({x: 1, y: 2}) { Point => \"point\", _ => \"other\" }", "point"},
+		{types + "switch ({x: 1}) { Point => \"point\", _ => \"other\" }", "other"},
+		{"let describe = func(x) { switch (x) { 0 => \"zero\", n => if (n > 0) { \"positive\" } else { \"negative\" } } }; [describe(0), describe(3), describe(-3)]", "[zero, positive, negative]"},
+	}
+
+	for _, tt := range tests {
+		val := testEval(tt.input)
+		if val == nil {
+			t.Errorf("%q: evaluated to nothing", tt.input)
+			continue
+		}
+		if val.Inspect() != tt.expecc {
+			t.Errorf("%q: expecc %s, received %s", tt.input, tt.expecc, val.Inspect())
+		}
+	}
+
+	errors := []struct {
+		input  string
+		expecc string
+	}{
+		{"switch (3) { 1 => 1, 2 => 2 }", "ERROR: 1:1: no switch arm matched 3 (INTEGER)"},
+		{"let x = 1; x + switch (x) {}", "ERROR: 1:16: no switch arm matched 1 (INTEGER)"},
+		{"switch (y) { _ => 1 }", "ERROR: 1:9: identifier not found: y"},
+		{"switch (1) { 1 => 1 / 0 }", "ERROR: 1:21: division by zero"},
+	}
+
+	for _, tt := range errors {
+		val := testEval(tt.input)
+		if val == nil || val.Inspect() != tt.expecc {
+			t.Errorf("%q: expecc %s, received %v", tt.input, tt.expecc, val)
+		}
+	}
+}
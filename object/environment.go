@@ -0,0 +1,53 @@
+package object
+
+// Environment binds names to objects. Values and types
+// live in separate namespaces, as they do for the type
+// checker. Lookups fall back to the outer environment,
+// which is how nested scopes see their parents' names
+type Environment struct {
+	store map[string]Object
+	types map[string]Type
+	outer *Environment
+}
+
+func NewEnvironment() *Environment {
+	return &Environment{
+		store: make(map[string]Object),
+		types: make(map[string]Type),
+	}
+}
+
+// NewEnclosedEnvironment creates a scope nested in outer
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		return e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+// Set binds name in this scope, shadowing
+// any binding of the same name outside it
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+func (e *Environment) GetType(name string) (Type, bool) {
+	t, ok := e.types[name]
+	if !ok && e.outer != nil {
+		return e.outer.GetType(name)
+	}
+	return t, ok
+}
+
+func (e *Environment) SetType(name string, t Type) Type {
+	e.types[name] = t
+	return t
+}
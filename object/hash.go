@@ -0,0 +1,85 @@
+package object
+
+import "strings"
+
+// HashKey identifies the value of a hash key. Two
+// keys are the same if they're of the same kind and
+// value, and being comparable, HashKeys may key a
+// Go map directly
+type HashKey struct {
+	Kind  ObjectKind
+	Value uint64
+	Text  string // the value of string keys
+}
+
+// Hashable is implemented by the objects
+// which may be used as the keys of a hash
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Kind: INTEGER, Value: uint64(i.Value)}
+}
+
+func (b *Boolean) HashKey() HashKey {
+	if b.Value {
+		return HashKey{Kind: BOOLEAN, Value: 1}
+	}
+	return HashKey{Kind: BOOLEAN}
+}
+
+func (s *String) HashKey() HashKey {
+	return HashKey{Kind: STRING, Text: s.Value}
+}
+
+// HashPair is an entry of a hash, keeping
+// the key itself alongside its value
+type HashPair struct {
+	Key   Hashable
+	Value Object
+}
+
+// Hash is an immutable mapping of keys to values.
+// Its pairs keep the order they were first given in
+type Hash struct {
+	Keys  []HashKey
+	Pairs map[HashKey]HashPair
+}
+
+// NewHash makes an empty hash
+func NewHash() *Hash {
+	return &Hash{Keys: []HashKey{}, Pairs: map[HashKey]HashPair{}}
+}
+
+func (h *Hash) Kind() ObjectKind { return HASH }
+func (h *Hash) Inspect() string {
+	pairs := []string{}
+	for _, key := range h.Keys {
+		pair := h.Pairs[key]
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+	// {} would read as the empty record
+	if len(pairs) == 0 {
+		return "{:}"
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// Get gives back the value of key, if h has one
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := h.Pairs[key.HashKey()]
+	return pair.Value, ok
+}
+
+// Set adds key to h, or replaces its value if h has it
+// already. Hashes are immutable once made, so Set is
+// only for building them up
+func (h *Hash) Set(key Hashable, value Object) {
+	k := key.HashKey()
+	if _, ok := h.Pairs[k]; !ok {
+		h.Keys = append(h.Keys, k)
+	}
+	h.Pairs[k] = HashPair{Key: key, Value: value}
+}
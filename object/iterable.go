@@ -0,0 +1,147 @@
+package object
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Iterator steps through the elements of a
+// sequence, giving false once there are no more
+type Iterator interface {
+	Next() (Object, bool)
+}
+
+// IteratorFunc lets a plain function be an Iterator
+type IteratorFunc func() (Object, bool)
+
+func (f IteratorFunc) Next() (Object, bool) { return f() }
+
+// Iterable is anything the builtins can work through
+// an element at a time, without needing all of them
+// in memory at once
+type Iterable interface {
+	Object
+	Iterate() Iterator
+}
+
+func (a *Array) Iterate() Iterator {
+	i := 0
+	return IteratorFunc(func() (Object, bool) {
+		if i >= len(a.Elements) {
+			return nil, false
+		}
+		i++
+		return a.Elements[i-1], true
+	})
+}
+
+// Range is the integers from Start to End, Step
+// apart. End itself is only included if Inclusive
+// is set and the steps land on it. The integers
+// are worked out as they're needed, so even
+// [1..1_000_000_000] takes no room
+type Range struct {
+	Start, End, Step int64
+	Inclusive        bool
+}
+
+func (r *Range) Kind() ObjectKind { return RANGE }
+func (r *Range) Inspect() string {
+	var out strings.Builder
+
+	out.WriteRune('[')
+	out.WriteString(strconv.FormatInt(r.Start, 10))
+	if r.Step != 1 {
+		out.WriteString(", ")
+		out.WriteString(strconv.FormatInt(r.Start+r.Step, 10))
+	}
+	out.WriteString("..")
+	if !r.Inclusive {
+		out.WriteRune('<')
+	}
+	out.WriteString(strconv.FormatInt(r.End, 10))
+	out.WriteRune(']')
+
+	return out.String()
+}
+
+// Len counts the integers in the range. The arithmetic
+// is unsigned, so that ranges spanning most of int64
+// don't overflow. Every int64 at once is one more than
+// a uint64 holds though, so that range is cut short
+// by one rather than wrapping around to nothing
+func (r *Range) Len() uint64 {
+	start, end := uint64(r.Start), uint64(r.End)
+	step := uint64(r.Step)
+
+	// count downwards ranges as their mirror image
+	ascending := r.Step > 0
+	if !ascending {
+		start, end = end, start
+		step = -step
+	}
+
+	switch {
+	case ascending && r.Start > r.End, !ascending && r.Start < r.End:
+		return 0
+	case r.Inclusive:
+		n := (end - start) / step
+		if n == math.MaxUint64 {
+			return n
+		}
+		return n + 1
+	case r.Start == r.End:
+		return 0
+	default:
+		return (end-start-1)/step + 1
+	}
+}
+
+// At gives back the i'th integer of the range,
+// counting from 0, which must be less than Len
+func (r *Range) At(i uint64) *Integer {
+	return &Integer{Value: int64(uint64(r.Start) + i*uint64(r.Step))}
+}
+
+func (r *Range) Iterate() Iterator {
+	n, i := r.Len(), uint64(0)
+	return IteratorFunc(func() (Object, bool) {
+		if i >= n {
+			return nil, false
+		}
+		i++
+		return r.At(i - 1), true
+	})
+}
+
+// Sequence is a list worked out lazily, such as
+// the result of mapping over a range. Each time
+// it is iterated the work is done again
+type Sequence struct {
+	Iter func() Iterator
+}
+
+// inspected is how many elements of a sequence
+// Inspect shows, as it may be a very long one
+const inspected = 10
+
+func (s *Sequence) Kind() ObjectKind  { return SEQUENCE }
+func (s *Sequence) Iterate() Iterator { return s.Iter() }
+func (s *Sequence) Inspect() string {
+	elements := []string{}
+
+	it := s.Iterate()
+	for obj, ok := it.Next(); ok; obj, ok = it.Next() {
+		if len(elements) == inspected {
+			elements = append(elements, "...")
+			break
+		}
+		elements = append(elements, obj.Inspect())
+		if obj.Kind() == ERROR {
+			break
+		}
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
@@ -1,8 +1,13 @@
 package object
 
 import (
+	"bytes"
 	"fmt"
 	"strconv"
+	"strings"
+
+	"github.com/SCKelemen/oak/ast"
+	"github.com/SCKelemen/oak/token"
 )
 
 type Integer struct {
@@ -14,6 +19,20 @@ func (i *Integer) Inspect() string {
 }
 func (i *Integer) Kind() ObjectKind { return INTEGER }
 
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Kind() ObjectKind { return FLOAT }
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	// keep whole floats from passing for integers
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -23,11 +42,120 @@ func (b *Boolean) Inspect() string {
 	return fmt.Sprintf("%t", b.Value)
 }
 
+type String struct {
+	Value string
+}
+
+func (s *String) Kind() ObjectKind { return STRING }
+func (s *String) Inspect() string  { return s.Value }
+
 type Null struct{}
 
 func (n *Null) Kind() ObjectKind { return NULL }
 func (n *Null) Inspect() string  { return "null" }
 
+// Error is what evaluation produces when something
+// goes wrong. It carries where in the source the
+// problem was, if we know
+type Error struct {
+	Message string
+	Pos     token.Position
+}
+
+func (e *Error) Kind() ObjectKind { return ERROR }
+func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR: " + e.Pos.String() + ": " + e.Message
+	}
+	return "ERROR: " + e.Message
+}
+
+// ReturnValue wraps the value of a return statement
+// while it unwinds through the enclosing blocks
+type ReturnValue struct {
+	Value Object
+}
+
+func (rv *ReturnValue) Kind() ObjectKind { return RETURN_VALUE }
+func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
+
+// Function is a function value. It keeps the
+// environment it was defined in, so that the
+// body can see the names around it when it is
+// invoked later on
+type Function struct {
+	Arguments []*ast.Identifier
+	Body      *ast.BlockStatement
+	Env       *Environment
+}
+
+func (f *Function) Kind() ObjectKind { return FUNCTION }
+func (f *Function) Inspect() string {
+	var out bytes.Buffer
+
+	args := []string{}
+	for _, arg := range f.Arguments {
+		args = append(args, arg.String())
+	}
+
+	out.WriteString("func(")
+	out.WriteString(strings.Join(args, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(f.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
+// Array is an immutable list of objects
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Kind() ObjectKind { return ARRAY }
+func (a *Array) Inspect() string {
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, e.Inspect())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// Record is an immutable set of named fields, which
+// keep the order they were first given in
+type Record struct {
+	Names  []string
+	Fields map[string]Object
+}
+
+func (r *Record) Kind() ObjectKind { return RECORD }
+func (r *Record) Inspect() string {
+	fields := []string{}
+	for _, name := range r.Names {
+		fields = append(fields, name+": "+r.Fields[name].Inspect())
+	}
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+
+// Get gives back the field called name, if r has one
+func (r *Record) Get(name string) (Object, bool) {
+	obj, ok := r.Fields[name]
+	return obj, ok
+}
+
+// BuiltinFunction implements a builtin. Errors it
+// returns without a position are given the one of
+// the invocation
+type BuiltinFunction func(args ...Object) Object
+
+type Builtin struct {
+	Name string
+	Fn   BuiltinFunction
+}
+
+func (b *Builtin) Kind() ObjectKind { return BUILTIN }
+func (b *Builtin) Inspect() string  { return "builtin " + b.Name }
+
 type ObjectKind int
 
 type Object interface {
@@ -38,15 +166,40 @@ type Object interface {
 const (
 	ILLEGAL ObjectKind = iota
 	INTEGER
+	FLOAT
 	BOOLEAN
+	STRING
 	NULL
+	TYPE
+	ERROR
+	RETURN_VALUE
+	FUNCTION
+	BUILTIN
+	ARRAY
+	RANGE
+	SEQUENCE
+	RECORD
+	HASH
 )
 
 var types = [...]string{
 	ILLEGAL: "ILLEGAL",
 	INTEGER: "INTEGER",
+	FLOAT:   "FLOAT",
 	BOOLEAN: "BOOLEAN",
+	STRING:  "STRING",
 	NULL:    "NULL",
+	TYPE:    "TYPE",
+	ERROR:   "ERROR",
+
+	RETURN_VALUE: "RETURN_VALUE",
+	FUNCTION:     "FUNCTION",
+	BUILTIN:      "BUILTIN",
+	ARRAY:        "ARRAY",
+	RANGE:        "RANGE",
+	SEQUENCE:     "SEQUENCE",
+	RECORD:       "RECORD",
+	HASH:         "HASH",
 }
 
 func (kind ObjectKind) String() string {
@@ -0,0 +1,147 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Type is implemented by objects describing a set
+// of values. Contains reports whether obj is one
+// of them
+type Type interface {
+	Object
+	Contains(obj Object) bool
+}
+
+// Primitive is one of the builtin types, which
+// every object of the given kind inhabits
+type Primitive struct {
+	Name string
+	Of   ObjectKind
+}
+
+func (p *Primitive) Kind() ObjectKind         { return TYPE }
+func (p *Primitive) Inspect() string          { return p.Name }
+func (p *Primitive) Contains(obj Object) bool { return obj != nil && obj.Kind() == p.Of }
+
+// NamedType is a type introduced by a type declaration.
+// Type is filled in once the declaration has been
+// evaluated, so types may refer to each other
+// regardless of the order they're declared in
+type NamedType struct {
+	Name string
+	Type Type
+}
+
+func (nt *NamedType) Kind() ObjectKind { return TYPE }
+func (nt *NamedType) Inspect() string {
+	if nt.Type == nil {
+		return nt.Name
+	}
+	return nt.Name + " = " + nt.Type.Inspect()
+}
+func (nt *NamedType) Contains(obj Object) bool {
+	return nt.Type != nil && nt.Type.Contains(obj)
+}
+
+// Literal is a singleton type, the
+// only inhabitant of which is Value
+type Literal struct {
+	Value Object
+}
+
+func (l *Literal) Kind() ObjectKind { return TYPE }
+func (l *Literal) Inspect() string  { return l.Value.Inspect() }
+func (l *Literal) Contains(obj Object) bool {
+	switch value := l.Value.(type) {
+	case *Integer:
+		other, ok := obj.(*Integer)
+		return ok && other.Value == value.Value
+	case *Boolean:
+		other, ok := obj.(*Boolean)
+		return ok && other.Value == value.Value
+	}
+	return false
+}
+
+// Union is inhabited by the values of any of its variants
+type Union struct {
+	Variants []Type
+}
+
+func (u *Union) Kind() ObjectKind { return TYPE }
+func (u *Union) Inspect() string {
+	var out bytes.Buffer
+
+	for i, v := range u.Variants {
+		if i > 0 {
+			out.WriteRune(' ')
+		}
+		out.WriteString("| ")
+		out.WriteString(typeName(v))
+	}
+
+	return out.String()
+}
+func (u *Union) Contains(obj Object) bool {
+	for _, v := range u.Variants {
+		if v.Contains(obj) {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersection is inhabited by values
+// belonging to every one of its types
+type Intersection struct {
+	Types []Type
+}
+
+func (i *Intersection) Kind() ObjectKind { return TYPE }
+func (i *Intersection) Inspect() string {
+	types := []string{}
+	for _, t := range i.Types {
+		types = append(types, typeName(t))
+	}
+	return strings.Join(types, " & ")
+}
+func (i *Intersection) Contains(obj Object) bool {
+	for _, t := range i.Types {
+		if !t.Contains(obj) {
+			return false
+		}
+	}
+	return true
+}
+
+// Property is inhabited by values having
+// a member Name whose value is of Type
+type Property struct {
+	Name string
+	Type Type
+}
+
+func (p *Property) Kind() ObjectKind { return TYPE }
+func (p *Property) Inspect() string  { return p.Name + ": " + typeName(p.Type) }
+
+func (p *Property) Contains(obj Object) bool {
+	record, ok := obj.(*Record)
+	if !ok {
+		return false
+	}
+	field, ok := record.Get(p.Name)
+	return ok && p.Type != nil && p.Type.Contains(field)
+}
+
+// typeName refers to named types by name rather
+// than spelling out their whole definition
+func typeName(t Type) string {
+	if named, ok := t.(*NamedType); ok {
+		return named.Name
+	}
+	if t == nil {
+		return "?"
+	}
+	return t.Inspect()
+}
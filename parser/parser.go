@@ -1,12 +1,16 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/SCKelemen/oak/ast"
+	"github.com/SCKelemen/oak/diagnostic"
 	"github.com/SCKelemen/oak/scanner"
 	"github.com/SCKelemen/oak/token"
+	"github.com/SCKelemen/oak/util"
 )
 
 type Parser struct {
@@ -14,7 +18,7 @@ type Parser struct {
 	currentToken token.Token
 	peekToken    token.Token
 
-	errors []string
+	errors []diagnostic.Diagnostic
 
 	prefixParseFns map[token.TokenKind]prefixParseFn
 	infixParseFns  map[token.TokenKind]infixParseFn
@@ -24,13 +28,15 @@ type Parser struct {
 func New(lxr *scanner.Scanner) *Parser {
 	p := &Parser{
 		lxr:    lxr,
-		errors: []string{},
+		errors: []diagnostic.Diagnostic{},
 	}
 
 	// register functions
 	p.prefixParseFns = make(map[token.TokenKind]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.NEG, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
@@ -38,6 +44,10 @@ func New(lxr *scanner.Scanner) *Parser {
 	p.registerPrefix(token.LPAREN, p.parseExpressionGroup)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNC, p.parseFunctionLiteral)
+	p.registerPrefix(token.SWITCH, p.parseSwitchExpression)
+	p.registerPrefix(token.SPREAD, p.parseSpreadExpression)
+	p.registerPrefix(token.LBRACK, p.parseArrayOrRange)
+	p.registerPrefix(token.LBRACE, p.parseBraceLiteral)
 
 	p.infixParseFns = make(map[token.TokenKind]infixParseFn)
 	p.registerInfix(token.SUM, p.parseInfixExpression)
@@ -48,7 +58,15 @@ func New(lxr *scanner.Scanner) *Parser {
 	p.registerInfix(token.NEQL, p.parseInfixExpression)
 	p.registerInfix(token.LCHEV, p.parseInfixExpression)
 	p.registerInfix(token.RCHEV, p.parseInfixExpression)
+	p.registerInfix(token.LEQL, p.parseInfixExpression)
+	p.registerInfix(token.GEQL, p.parseInfixExpression)
+	p.registerInfix(token.LAND, p.parseInfixExpression)
+	p.registerInfix(token.LOR, p.parseInfixExpression)
+	p.registerInfix(token.FPIPE, p.parsePipeExpression)
+	p.registerInfix(token.RPIPE, p.parsePipeExpression)
 	p.registerInfix(token.LPAREN, p.parseInvocationExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.LBRACK, p.parseIndexExpression)
 
 	// load the first 2 tokens
 	p.nextToken()
@@ -57,8 +75,38 @@ func New(lxr *scanner.Scanner) *Parser {
 	return p
 }
 
-func (p *Parser) Errors() []string {
-	return p.errors
+// diagnostic codes reported by the parser
+const (
+	CodeUnexpectedToken = "P0001"
+	CodeExpectedExpr    = "P0002"
+	CodeInvalidInteger  = "P0003"
+	CodeExpectedType    = "P0004"
+	CodeInvalidString   = "P0005"
+	CodeOverflow        = "P0006"
+	CodeInvalidFloat    = "P0007"
+	CodeExpectedPattern = "P0008"
+)
+
+// Errors gives back the problems found while parsing,
+// along with those the scanner ran into. Scanner errors
+// are slotted in by position, parser errors stay in
+// the order they were found
+func (p *Parser) Errors() []diagnostic.Diagnostic {
+	scanned := p.lxr.Errors()
+	if len(scanned) == 0 {
+		return p.errors
+	}
+
+	errors := make([]diagnostic.Diagnostic, 0, len(scanned)+len(p.errors))
+	parsed := p.errors
+	for len(scanned) > 0 || len(parsed) > 0 {
+		if len(parsed) == 0 || len(scanned) > 0 && scanned[0].Span.Start.Offset <= parsed[0].Span.Start.Offset {
+			errors, scanned = append(errors, scanned[0]), scanned[1:]
+		} else {
+			errors, parsed = append(errors, parsed[0]), parsed[1:]
+		}
+	}
+	return errors
 }
 
 func (p *Parser) registerPrefix(TokenKind token.TokenKind, fn prefixParseFn) {
@@ -82,24 +130,272 @@ func (p *Parser) parseTypeDeclaration() *ast.TypeDeclarationStatement {
 	}
 
 	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
-	if !p.expectPeek(token.EQL) {
+	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
-	// TODO: skip shit
-	for !p.currentTokenIs(token.SEMI) {
+
+	p.nextToken()
+	stmt.Value = p.parseTypeExpression()
+	if stmt.Value == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMI) {
 		p.nextToken()
 	}
 	return stmt
 }
 
+// parseLabelledTypeDeclaration handles the label
+// form of a type declaration, where the current
+// token is the label:
+//
+//	Lexer: type
+//	  = input: string
+//	  & current: char
+//
+// or with the dot notation sugar:
+//
+//	Lexer: type
+//	  .input: string
+//	  .current: char
+func (p *Parser) parseLabelledTypeDeclaration() *ast.TypeDeclarationStatement {
+	name := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+	if !p.expectPeek(token.TYPE) {
+		return nil
+	}
+
+	stmt := &ast.TypeDeclarationStatement{Token: p.currentToken, Name: name, Labelled: true}
+
+	if p.peekTokenIs(token.DOT) {
+		stmt.Value = p.parseDotProperties()
+	} else {
+		if !p.expectPeek(token.ASSIGN) {
+			return nil
+		}
+		p.nextToken()
+		stmt.Value = p.parseTypeExpression()
+	}
+	if stmt.Value == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMI) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseDotProperties reads .name: Type members
+// for as long as they keep coming, composing
+// them the same way & would
+func (p *Parser) parseDotProperties() ast.Expression {
+	members := &ast.IntersectionType{}
+
+	for p.peekTokenIs(token.DOT) {
+		p.nextToken()
+		dot := p.currentToken
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		prop := p.parsePropertyType()
+		if prop == nil {
+			return nil
+		}
+		prop.Token = dot
+
+		members.Types = append(members.Types, prop)
+	}
+
+	if len(members.Types) == 1 {
+		return members.Types[0]
+	}
+	return members
+}
+
+// parseTypeExpression parses the right hand side
+// of a type declaration, leaving the current
+// token on the last token of the type. Unions
+// bind loosest, so A & B | C is (A & B) | C
+func (p *Parser) parseTypeExpression() ast.Expression {
+	union := &ast.UnionType{Token: p.currentToken}
+
+	// the leading pipe is optional
+	if !p.currentTokenIs(token.PIPE) {
+		first := p.parseIntersectionType()
+		if first == nil || !p.peekTokenIs(token.PIPE) {
+			return first
+		}
+		variant := &ast.UnionVariant{Token: union.Token, Type: first}
+		union.Variants = append(union.Variants, variant)
+		p.nextToken()
+		union.Token = p.currentToken
+	}
+
+	for {
+		variant := &ast.UnionVariant{Token: p.currentToken}
+		p.nextToken()
+		if variant.Type = p.parseIntersectionType(); variant.Type == nil {
+			return nil
+		}
+		union.Variants = append(union.Variants, variant)
+
+		if !p.peekTokenIs(token.PIPE) {
+			break
+		}
+		p.nextToken()
+	}
+
+	return union
+}
+
+func (p *Parser) parseIntersectionType() ast.Expression {
+	first := p.parseTypeTerm()
+	if first == nil || !p.peekTokenIs(token.AMP) {
+		return first
+	}
+
+	typ := &ast.IntersectionType{Token: p.peekToken, Types: []ast.Expression{first}}
+	for p.peekTokenIs(token.AMP) {
+		p.nextToken() // consume the &
+		p.nextToken() // load the next term
+
+		term := p.parseTypeTerm()
+		if term == nil {
+			return nil
+		}
+		typ.Types = append(typ.Types, term)
+	}
+
+	return typ
+}
+
+func (p *Parser) parseTypeTerm() ast.Expression {
+	switch p.currentToken.TokenKind {
+	case token.IDENT:
+		if p.peekTokenIs(token.COLON) {
+			if prop := p.parsePropertyType(); prop != nil {
+				return prop
+			}
+			return nil
+		}
+		return &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	case token.INT, token.TRUE, token.FALSE:
+		return p.parseLiteralType()
+	case token.FUNC:
+		return p.parseFunctionType()
+	case token.LPAREN:
+		p.nextToken()
+		typ := p.parseTypeExpression()
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		return typ
+	default:
+		p.expectedTypeError()
+		return nil
+	}
+}
+
+// parseFunctionType parses func(int, bool): int
+func (p *Parser) parseFunctionType() ast.Expression {
+	typ := &ast.FunctionType{Token: p.currentToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		param := p.parseTypeExpression()
+		if param == nil {
+			return nil
+		}
+		typ.Params = append(typ.Params, param)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+	p.nextToken()
+
+	if typ.Result = p.parseTypeTerm(); typ.Result == nil {
+		return nil
+	}
+	return typ
+}
+
+func (p *Parser) parseLiteralType() ast.Expression {
+	typ := &ast.LiteralType{Token: p.currentToken}
+
+	if p.currentTokenIs(token.INT) {
+		typ.Value = p.parseIntegerLiteral()
+	} else {
+		typ.Value = p.parseBoolean()
+	}
+	if typ.Value == nil {
+		return nil
+	}
+	return typ
+}
+
+// parsePropertyType parses name: Type, where
+// the current token is the name
+func (p *Parser) parsePropertyType() *ast.PropertyType {
+	prop := &ast.PropertyType{Token: p.currentToken}
+	prop.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+	p.nextToken()
+
+	prop.Type = p.parseTypeTerm()
+	if prop.Type == nil {
+		return nil
+	}
+	return prop
+}
+
+func (p *Parser) expectedTypeError() {
+	p.errors = append(p.errors, diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Span:     p.currentToken.Span,
+		Code:     CodeExpectedType,
+		Message:  fmt.Sprintf("expected a type, received %s", p.currentToken.TokenKind),
+		Found:    p.currentToken.TokenKind,
+	})
+}
+
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.currentToken}
 
+	// a bare return gives back null
+	if p.peekTokenIs(token.SEMI) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) {
+		if p.peekTokenIs(token.SEMI) {
+			p.nextToken()
+		}
+		return stmt
+	}
+
 	p.nextToken()
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
-	for !p.currentTokenIs(token.SEMI) {
+	if p.peekTokenIs(token.SEMI) {
 		p.nextToken()
 	}
 
@@ -117,6 +413,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	blocc.Rbrace = p.currentToken
 
 	return blocc
 }
@@ -124,7 +421,19 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.currentToken.TokenKind {
 	case token.TYPE:
-		return p.parseTypeDeclaration()
+		// don't hand back a typed nil on failure
+		if stmt := p.parseTypeDeclaration(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.IDENT:
+		if p.peekTokenIs(token.COLON) {
+			if stmt := p.parseLabelledTypeDeclaration(); stmt != nil {
+				return stmt
+			}
+			return nil
+		}
+		return p.parseExpressionStatement()
 	case token.LET:
 		return p.parseLetStatement()
 	case token.RETURN:
@@ -172,10 +481,89 @@ func (p *Parser) parseIdentifier() ast.Expression {
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.currentToken}
 
-	value, err := strconv.ParseInt(p.currentToken.Literal, 0, 64)
+	literal := p.currentToken.Literal
+
+	// Go would read 0755 as octal, which is
+	// too easy to do by accident
+	if len(literal) > 1 && literal[0] == '0' && util.IsNumericChar(rune(literal[1])) {
+		p.numberError(CodeInvalidInteger, "invalid integer literal %s, octal literals are written 0o%s", literal, strings.TrimLeft(literal, "0_"))
+		return nil
+	}
+
+	value, err := strconv.ParseInt(literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.currentToken.Literal)
-		p.errors = append(p.errors, msg)
+		if errors.Is(err, strconv.ErrRange) {
+			p.numberError(CodeOverflow, "integer literal %s overflows int", literal)
+		} else {
+			p.numberError(CodeInvalidInteger, "invalid integer literal %s", literal)
+		}
+		return nil
+	}
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.currentToken}
+	literal := p.currentToken.Literal
+
+	value, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			p.numberError(CodeOverflow, "float literal %s overflows float", literal)
+		} else {
+			p.numberError(CodeInvalidFloat, "invalid float literal %s", literal)
+		}
+		return nil
+	}
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) numberError(code, format string, args ...interface{}) {
+	p.errors = append(p.errors, diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Span:     p.currentToken.Span,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// nodeError reports a problem with the
+// whole of a node parsed already
+func (p *Parser) nodeError(node ast.Node, code, format string, args ...interface{}) {
+	p.errors = append(p.errors, diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Span:     token.Span{Start: node.Pos(), End: node.End()},
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	lit := &ast.StringLiteral{Token: p.currentToken}
+
+	value, err := unquote(p.currentToken.Literal)
+	if err != nil {
+		// point at the offending escape, strings
+		// never span lines so columns line up
+		span := p.currentToken.Span
+		span.Start.Offset += err.offset
+		span.Start.Column += err.offset
+		if err.length > 0 {
+			span.End = span.Start
+			span.End.Offset += err.length
+			span.End.Column += err.length
+		}
+
+		p.errors = append(p.errors, diagnostic.Diagnostic{
+			Severity: diagnostic.Error,
+			Span:     span,
+			Code:     CodeInvalidString,
+			Message:  err.message,
+		})
 		return nil
 	}
 	lit.Value = value
@@ -190,7 +578,15 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Arguments = p.parseFunctionArgs()
+	lit.Arguments, lit.ArgumentTypes = p.parseFunctionArgs()
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		if lit.ReturnType = p.parseTypeExpression(); lit.ReturnType == nil {
+			return nil
+		}
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -201,36 +597,56 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return lit
 }
 
-func (p *Parser) parseFunctionArgs() []*ast.Identifier {
+// parseFunctionArgs reads the argument names, along
+// with any type annotations, x: int. The types are
+// nil for arguments without one
+func (p *Parser) parseFunctionArgs() ([]*ast.Identifier, []ast.Expression) {
 	ids := []*ast.Identifier{}
+	types := []ast.Expression{}
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return ids
+		return ids, types
 	}
 
 	p.nextToken()
 
 	ident := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
 	ids = append(ids, ident)
+	types = append(types, p.parseAnnotation())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken() // consume the comma
 		p.nextToken() // load token after comma
 		ident := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
 		ids = append(ids, ident)
+		types = append(types, p.parseAnnotation())
 	}
 
 	if !p.expectPeek(token.RPAREN) {
+		return nil, nil
+	}
+
+	return ids, types
+}
+
+// parseAnnotation parses the optional : Type
+// following a name, the same way type members
+// are annotated
+func (p *Parser) parseAnnotation() ast.Expression {
+	if !p.peekTokenIs(token.COLON) {
 		return nil
 	}
+	p.nextToken()
+	p.nextToken()
 
-	return ids
+	return p.parseTypeExpression()
 }
 
 func (p *Parser) parseInvocationExpression(function ast.Expression) ast.Expression {
 	exp := &ast.InvocationExpression{Token: p.currentToken, Function: function}
 	exp.Arguments = p.parseInvocationArguments()
+	exp.Rparen = p.currentToken
 	return exp
 }
 
@@ -295,6 +711,114 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expr
 }
 
+// parseSwitchExpression parses a switch, the arms of
+// which are separated by commas:
+//
+//	switch (code) {
+//	    Ok => "fine",
+//	    NotFound => "missing",
+//	    _ => "something else",
+//	}
+func (p *Parser) parseSwitchExpression() ast.Expression {
+	expr := &ast.SwitchExpression{Token: p.currentToken, Arms: []*ast.SwitchArm{}}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	if expr.Value = p.parseExpression(LOWEST); expr.Value == nil {
+		return nil
+	}
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm := &ast.SwitchArm{}
+		if arm.Pattern = p.parsePattern(); arm.Pattern == nil {
+			return nil
+		}
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+
+		p.nextToken()
+		if arm.Result = p.parseExpression(LOWEST); arm.Result == nil {
+			return nil
+		}
+		expr.Arms = append(expr.Arms, arm)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	expr.Rbrace = p.currentToken
+	return expr
+}
+
+// parsePattern parses the pattern of a switch arm,
+// which is a literal, a name, or a record pattern
+func (p *Parser) parsePattern() ast.Expression {
+	switch p.currentToken.TokenKind {
+	case token.IDENT:
+		return p.parseIdentifier()
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE:
+		return p.prefixParseFns[p.currentToken.TokenKind]()
+	case token.NEG:
+		if p.peekTokenIs(token.INT) || p.peekTokenIs(token.FLOAT) {
+			if expr := p.parsePrefixExpression(); expr.(*ast.PrefixExpression).Right != nil {
+				return expr
+			}
+			return nil
+		}
+	case token.LBRACE:
+		return p.parseRecordPattern()
+	}
+
+	p.errors = append(p.errors, diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Span:     p.currentToken.Span,
+		Code:     CodeExpectedPattern,
+		Message:  fmt.Sprintf("expected a pattern, received %s", p.currentToken.TokenKind),
+		Found:    p.currentToken.TokenKind,
+	})
+	return nil
+}
+
+// parseRecordPattern parses {name: pattern, ...}, where
+// a name on its own is short for name: name
+func (p *Parser) parseRecordPattern() ast.Expression {
+	pattern := &ast.RecordPattern{Token: p.currentToken, Fields: []*ast.RecordField{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		name := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+		field := &ast.RecordField{Name: name, Value: name}
+
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			if field.Value = p.parsePattern(); field.Value == nil {
+				return nil
+			}
+		}
+		pattern.Fields = append(pattern.Fields, field)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	pattern.Rbrace = p.currentToken
+	return pattern
+}
+
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
@@ -329,8 +853,14 @@ func (p *Parser) expectPeek(t token.TokenKind) bool {
 }
 
 func (p *Parser) peekError(t token.TokenKind) {
-	msg := fmt.Sprintf("expected next token to be '%s', received %s", t, p.peekToken.TokenKind)
-	p.errors = append(p.errors, msg)
+	p.errors = append(p.errors, diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Span:     p.peekToken.Span,
+		Code:     CodeUnexpectedToken,
+		Message:  fmt.Sprintf("expected next token to be '%s', received %s", t, p.peekToken.TokenKind),
+		Expected: []token.TokenKind{t},
+		Found:    p.peekToken.TokenKind,
+	})
 }
 
 // pratt and whitney parsing engines
@@ -347,18 +877,28 @@ type Precedence int
 const (
 	_ Precedence = iota
 	LOWEST
-	EQUALITY   // ==
-	COMPARE    // > or <
-	SUMMATION  // +
-	PRODUCT    // *
-	PREFIX     // -x or !x
-	INVOCATION // aka Call, myfunction(x)
-
+	PIPELINE    // |> or <|
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
+	EQUALITY    // ==
+	COMPARE     // > or <
+	RANGE       // ..
+	SUMMATION   // +
+	PRODUCT     // *
+	PREFIX      // -x or !x
+	INVOCATION  // aka Call, myfunction(x)
+	INDEX       // xs[i] or r.field
 )
 
 func (p *Parser) noPrefixParseFn(t token.TokenKind) {
-	msg := fmt.Sprintf("no prefix parse function defined for TokenKind %s", t)
-	p.errors = append(p.errors, msg)
+	p.errors = append(p.errors, diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Span:     p.currentToken.Span,
+		Code:     CodeExpectedExpr,
+		Message:  fmt.Sprintf("no prefix parse function defined for TokenKind %s", t),
+		Found:    t,
+		Notes:    []diagnostic.Note{{Message: fmt.Sprintf("%s cannot start an expression", t)}},
+	})
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
@@ -386,6 +926,259 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// parsePipeExpression turns a pipe into the invocation
+// it stands for, passing the value as the last argument:
+//
+//	xs |> filter(isEven)  is  filter(isEven, xs)
+//	xs |> length          is  length(xs)
+//	f <| x                is  f(x)
+//	xs |> (f(a))          is  f(a)(xs)
+//
+// |> chains from the left, and <| from the right, so
+// f <| g <| x is f(g(x)). That's unlike F#, where <|
+// chains from the left as well, and it means a <| takes
+// in any |> after it, so f <| x |> g is f(g(x))
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	pipe := p.currentToken
+
+	precedence := p.currentPrecedence()
+	if pipe.TokenKind == token.RPIPE {
+		precedence--
+	}
+
+	p.nextToken()
+	right := p.parseExpression(precedence)
+	if right == nil || left == nil {
+		return nil
+	}
+
+	function, value := right, left
+	if pipe.TokenKind == token.RPIPE {
+		function, value = left, right
+	}
+
+	if call, ok := function.(*ast.InvocationExpression); ok && !call.Grouped {
+		args := append(append([]ast.Expression{}, call.Arguments...), value)
+		return &ast.InvocationExpression{Token: call.Token, Function: call.Function, Arguments: args, Rparen: call.Rparen}
+	}
+	// there's no ) to end on, so end on whatever came last
+	return &ast.InvocationExpression{Token: pipe, Function: function, Arguments: []ast.Expression{value}, Rparen: p.currentToken}
+}
+
+// parseArrayOrRange parses either an array literal
+// or a range, which both begin with [. Once an element
+// is followed by .., what came so far is a range:
+//
+//	[1, 2, 3]    an array
+//	[1..3]       a range
+//	[1, 3..9]    a range stepping by 2
+func (p *Parser) parseArrayOrRange() ast.Expression {
+	lbrack := p.currentToken
+	elements := []ast.Expression{}
+
+	for !p.peekTokenIs(token.RBRACK) {
+		p.nextToken()
+		var element ast.Expression
+		if p.currentTokenIs(token.SPREAD) {
+			element = p.parseSpreadExpression()
+		} else {
+			element = p.parseExpression(LOWEST)
+		}
+		if element == nil {
+			return nil
+		}
+		elements = append(elements, element)
+
+		if p.peekTokenIs(token.RANGE) {
+			return p.parseRangeExpression(lbrack, elements)
+		}
+		if !p.peekTokenIs(token.RBRACK) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	return &ast.ArrayLiteral{Token: lbrack, Elements: elements, Rbrack: p.currentToken}
+}
+
+// parseRangeExpression finishes off a range once the
+// .. has been seen, given the bounds before it. There
+// may be one, or two to give the step
+func (p *Parser) parseRangeExpression(lbrack token.Token, bounds []ast.Expression) ast.Expression {
+	// bad bounds are reported, but the rest of
+	// the range is still read to get past it
+	valid := true
+	if len(bounds) > 2 {
+		p.nodeError(bounds[2], CodeUnexpectedToken, "too many elements before .. in range, want at most 2")
+		valid = false
+	}
+	for _, bound := range bounds {
+		if _, ok := bound.(*ast.SpreadExpression); ok {
+			p.nodeError(bound, CodeUnexpectedToken, "cannot use %s as a range bound", bound)
+			valid = false
+		}
+	}
+
+	expr := &ast.RangeExpression{Token: lbrack, Start: bounds[0]}
+	if len(bounds) > 1 {
+		expr.Next = bounds[1]
+	}
+
+	p.nextToken()
+	if p.peekTokenIs(token.LCHEV) {
+		p.nextToken()
+		expr.Exclusive = true
+	}
+
+	p.nextToken()
+	if expr.Stop = p.parseExpression(LOWEST); expr.Stop == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.RBRACK) || !valid {
+		return nil
+	}
+	expr.Rbrack = p.currentToken
+
+	return expr
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	expr := &ast.IndexExpression{Token: p.currentToken, Left: left}
+
+	p.nextToken()
+	if expr.Index = p.parseExpression(LOWEST); expr.Index == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.RBRACK) {
+		return nil
+	}
+	expr.Rbrack = p.currentToken
+	return expr
+}
+
+func (p *Parser) parseSpreadExpression() ast.Expression {
+	exp := &ast.SpreadExpression{Token: p.currentToken}
+
+	p.nextToken()
+	if exp.Value = p.parseExpression(PREFIX); exp.Value == nil {
+		return nil
+	}
+	return exp
+}
+
+// parseBraceLiteral parses either a record or a hash,
+// which are both written in braces. If the first key
+// is a name, or something is spread in, it's a record:
+//
+//	{name: "oak", ...rest}          a record
+//	{200: "OK", 404: "Not Found"}   a hash
+//
+// The keys of a hash are expressions, so a hash keyed
+// by a variable starts with it in parentheses, as in
+// {(code): name}. {} is the empty record, so the empty
+// hash is written {:}
+func (p *Parser) parseBraceLiteral() ast.Expression {
+	switch p.peekToken.TokenKind {
+	case token.IDENT, token.SPREAD, token.RBRACE:
+		return p.parseRecordLiteral()
+	case token.COLON:
+		return p.parseEmptyHash()
+	default:
+		return p.parseHashLiteral()
+	}
+}
+
+// parseEmptyHash parses {:}
+func (p *Parser) parseEmptyHash() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.currentToken, Keys: []ast.Expression{}, Values: []ast.Expression{}}
+
+	p.nextToken()
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	hash.Rbrace = p.currentToken
+	return hash
+}
+
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.currentToken, Keys: []ast.Expression{}, Values: []ast.Expression{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+		if key == nil || !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+		if value == nil {
+			return nil
+		}
+		hash.Keys = append(hash.Keys, key)
+		hash.Values = append(hash.Values, value)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	hash.Rbrace = p.currentToken
+	return hash
+}
+
+// parseRecordLiteral parses {name: value, ...other},
+// allowing a trailing comma after the last entry
+func (p *Parser) parseRecordLiteral() ast.Expression {
+	record := &ast.RecordLiteral{Token: p.currentToken, Entries: []ast.Expression{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if p.peekTokenIs(token.SPREAD) {
+			p.nextToken()
+			spread := p.parseSpreadExpression()
+			if spread == nil {
+				return nil
+			}
+			record.Entries = append(record.Entries, spread)
+		} else {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			field := &ast.RecordField{Name: &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}}
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+
+			p.nextToken()
+			if field.Value = p.parseExpression(LOWEST); field.Value == nil {
+				return nil
+			}
+			record.Entries = append(record.Entries, field)
+		}
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	record.Rbrace = p.currentToken
+	return record
+}
+
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	expr := &ast.MemberExpression{Token: p.currentToken, Object: object}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	expr.Property = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	return expr
+}
+
 func (p *Parser) parseExpressionGroup() ast.Expression {
 	p.nextToken()
 	exp := p.parseExpression(LOWEST)
@@ -393,20 +1186,32 @@ func (p *Parser) parseExpressionGroup() ast.Expression {
 		return nil
 	}
 
+	if call, ok := exp.(*ast.InvocationExpression); ok {
+		call.Grouped = true
+	}
 	return exp
 }
 
 // all of these should probably move down to the lexer/scanner
 var precedences = map[token.TokenKind]Precedence{
+	token.FPIPE:  PIPELINE,
+	token.RPIPE:  PIPELINE,
+	token.LOR:    LOGICAL_OR,
+	token.LAND:   LOGICAL_AND,
 	token.EQL:    EQUALITY,
 	token.NEQL:   EQUALITY,
 	token.LCHEV:  COMPARE,
 	token.RCHEV:  COMPARE,
+	token.LEQL:   COMPARE,
+	token.GEQL:   COMPARE,
+	token.RANGE:  RANGE,
 	token.NEG:    SUMMATION,
 	token.SUM:    SUMMATION,
 	token.MUL:    PRODUCT,
 	token.QUO:    PRODUCT,
 	token.LPAREN: INVOCATION,
+	token.DOT:    INDEX,
+	token.LBRACK: INDEX,
 }
 
 func (p *Parser) peekPrecedence() Precedence {
@@ -431,6 +1236,7 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	}
 
 	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	stmt.Type = p.parseAnnotation()
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
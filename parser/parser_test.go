@@ -6,6 +6,7 @@ import (
 
 	"github.com/SCKelemen/oak/ast"
 	"github.com/SCKelemen/oak/scanner"
+	"github.com/SCKelemen/oak/token"
 )
 
 func TestTypeDeclarationStatements(t *testing.T) {
@@ -595,6 +596,30 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a + b + c * d / f + g)",
 			"add((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			"a <= b == b >= a",
+			"((a <= b) == (b >= a))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a < b && b != c",
+			"((a < b) && (b != c))",
+		},
+		{
+			"xs |> f || g",
+			"(f || g)(xs)",
+		},
+		{
+			"...a",
+			"...a",
+		},
 	}
 
 	for _, tt := range tests {
@@ -885,3 +910,982 @@ func testBooleanLiteral(t *testing.T, exp ast.Expression, value bool) bool {
 
 	return true
 }
+
+func TestErrorPositions(t *testing.T) {
+	input := "let x = 5;\nlet = 10;"
+
+	lxr := scanner.New(input)
+	p := New(lxr)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("parser had no errors")
+	}
+
+	expected := "2:5: expected next token to be 'IDENTITY', received ="
+	if errors[0].String() != expected {
+		t.Errorf("expected %q, received %q", expected, errors[0])
+	}
+	if errors[0].Code != CodeUnexpectedToken {
+		t.Errorf("expected code %s, received %s", CodeUnexpectedToken, errors[0].Code)
+	}
+	if len(errors[0].Expected) != 1 || errors[0].Expected[0] != token.IDENT {
+		t.Errorf("expected to want IDENT, received %v", errors[0].Expected)
+	}
+	if errors[0].Found != token.ASSIGN {
+		t.Errorf("expected to find ASSIGN, received %s", errors[0].Found)
+	}
+}
+
+func TestNodeSpans(t *testing.T) {
+	input := "add(1,\n  2 * 3)"
+
+	lxr := scanner.New(input)
+	p := New(lxr)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser had errors: %v", p.Errors())
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	if pos := stmt.Expression.Pos(); pos.Line != 1 || pos.Column != 1 {
+		t.Errorf("expression starts at %s, expected 1:1", pos)
+	}
+	if end := stmt.Expression.End(); end.Line != 2 || end.Column != 9 {
+		t.Errorf("expression ends at %s, expected 2:9", end)
+	}
+
+	call := stmt.Expression.(*ast.InvocationExpression)
+	if pos := call.Arguments[1].Pos(); pos.Line != 2 || pos.Column != 3 {
+		t.Errorf("second argument starts at %s, expected 2:3", pos)
+	}
+}
+
+func TestTypeDeclarationValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"type ReaderWriter = Reader & Writer;",
+			"type ReaderWriter = Reader & Writer;",
+		},
+		{
+			"type Point = x: int & y: int",
+			"type Point = x: int & y: int;",
+		},
+		{
+			"type Nested = (A & B) & C",
+			"type Nested = A & B & C;",
+		},
+		{
+			`Lexer: type
+			  = input:    string
+			  & current:  char
+			  & position: int
+			  & readPos:  int`,
+			"Lexer: type = input: string & current: char & position: int & readPos: int;",
+		},
+		{
+			`Lexer: type
+			  .input:    string
+			  .current:  char
+			  .position: int
+			  .readPos:  int`,
+			"Lexer: type = input: string & current: char & position: int & readPos: int;",
+		},
+		{
+			"Name: type .value: string",
+			"Name: type = value: string;",
+		},
+		{
+			"A: type = int B: type .b: A",
+			"A: type = int;B: type = b: A;",
+		},
+		{
+			"type T = A & B | C",
+			"type T = | A & B | C;",
+		},
+		{
+			"type T = (A) | B",
+			"type T = | A | B;",
+		},
+		{
+			"type T = x: int | bool",
+			"type T = | x: int | bool;",
+		},
+	}
+
+	for _, tt := range tests {
+		lxr := scanner.New(tt.input)
+		p := New(lxr)
+		program := p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 0 {
+			t.Errorf("parser had %d errors", len(errors))
+			for _, msg := range errors {
+				t.Errorf("parser error: %q", msg)
+			}
+			t.FailNow()
+		}
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected %q, received %q", tt.expected, actual)
+		}
+	}
+}
+
+func TestLabelledTypeDeclaration(t *testing.T) {
+	input := `
+	Lexer: type
+	  .input:   string
+	  .current: char
+	`
+
+	lxr := scanner.New(input)
+	p := New(lxr)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser had errors: %v", p.Errors())
+	}
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program doesn't have the correct number of statements. Expected 1, received %d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.TypeDeclarationStatement)
+	if !ok {
+		t.Fatalf("statement not of type *ast.TypeDeclarationStatement, received %T", program.Statements[0])
+	}
+	if !stmt.Labelled {
+		t.Errorf("statement was not marked as labelled")
+	}
+	if !testIdentifier(t, stmt.Name, "Lexer") {
+		return
+	}
+	if pos := stmt.Pos(); pos.Line != 2 || pos.Column != 2 {
+		t.Errorf("statement starts at %s, expected 2:2", pos)
+	}
+
+	intersection, ok := stmt.Value.(*ast.IntersectionType)
+	if !ok {
+		t.Fatalf("stmt.Value not of type *ast.IntersectionType, received %T", stmt.Value)
+	}
+
+	expected := []struct{ name, typ string }{
+		{"input", "string"},
+		{"current", "char"},
+	}
+	if len(intersection.Types) != len(expected) {
+		t.Fatalf("intersection has %d members, expected %d", len(intersection.Types), len(expected))
+	}
+	for i, tt := range expected {
+		prop, ok := intersection.Types[i].(*ast.PropertyType)
+		if !ok {
+			t.Fatalf("member %d not of type *ast.PropertyType, received %T", i, intersection.Types[i])
+		}
+		testIdentifier(t, prop.Name, tt.name)
+		testIdentifier(t, prop.Type, tt.typ)
+	}
+}
+
+func TestTypeDeclarationErrors(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedCode string
+	}{
+		{"type A = ;", CodeExpectedType},
+		{"type A = B &", CodeExpectedType},
+		{"Lexer: type input: string", CodeUnexpectedToken},
+		{"type A = (B & C", CodeUnexpectedToken},
+	}
+
+	for _, tt := range tests {
+		lxr := scanner.New(tt.input)
+		p := New(lxr)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("%q: parser had no errors", tt.input)
+			continue
+		}
+		if errors[0].Code != tt.expectedCode {
+			t.Errorf("%q: expected code %s, received %s (%s)", tt.input, tt.expectedCode, errors[0].Code, errors[0])
+		}
+	}
+}
+
+func TestUnionTypeDeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		variants int
+	}{
+		{
+			`type StatusCode =
+			    | InformationalCode
+			    | SuccessCode
+			    | RedirectionCode`,
+			"type StatusCode = | InformationalCode | SuccessCode | RedirectionCode;",
+			3,
+		},
+		{
+			"type Bool = True | False;",
+			"type Bool = | True | False;",
+			2,
+		},
+		{
+			"type Shape = | Circle & r: int | Square",
+			"type Shape = | Circle & r: int | Square;",
+			2,
+		},
+		{
+			"type One = | Only",
+			"type One = | Only;",
+			1,
+		},
+		{
+			"Status: type = | Ok | Err",
+			"Status: type = | Ok | Err;",
+			2,
+		},
+	}
+
+	for _, tt := range tests {
+		lxr := scanner.New(tt.input)
+		p := New(lxr)
+		program := p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 0 {
+			t.Errorf("parser had %d errors", len(errors))
+			for _, msg := range errors {
+				t.Errorf("parser error: %q", msg)
+			}
+			t.FailNow()
+		}
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected %q, received %q", tt.expected, actual)
+		}
+
+		stmt := program.Statements[0].(*ast.TypeDeclarationStatement)
+		union, ok := stmt.Value.(*ast.UnionType)
+		if !ok {
+			t.Fatalf("stmt.Value not of type *ast.UnionType, received %T", stmt.Value)
+		}
+		if len(union.Variants) != tt.variants {
+			t.Errorf("union has %d variants, expected %d", len(union.Variants), tt.variants)
+		}
+	}
+}
+
+func TestLiteralTypeDeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"type Ok = 200", 200},
+		{"type NotFound = 404;", 404},
+		{"type Yes = true", true},
+		{"type No = false", false},
+	}
+
+	for _, tt := range tests {
+		lxr := scanner.New(tt.input)
+		p := New(lxr)
+		program := p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 0 {
+			t.Errorf("parser had %d errors", len(errors))
+			for _, msg := range errors {
+				t.Errorf("parser error: %q", msg)
+			}
+			t.FailNow()
+		}
+
+		stmt := program.Statements[0].(*ast.TypeDeclarationStatement)
+		literal, ok := stmt.Value.(*ast.LiteralType)
+		if !ok {
+			t.Fatalf("stmt.Value not of type *ast.LiteralType, received %T", stmt.Value)
+		}
+		testLiteralExpression(t, literal.Value, tt.expected)
+	}
+}
+
+func TestUnionOfLiteralTypes(t *testing.T) {
+	input := "type Redirect = | 301 | 302 | MovedTemporarily"
+
+	lxr := scanner.New(input)
+	p := New(lxr)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser had errors: %v", p.Errors())
+	}
+
+	expected := "type Redirect = | 301 | 302 | MovedTemporarily;"
+	if program.String() != expected {
+		t.Errorf("expected %q, received %q", expected, program.String())
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let n: int = 5", "let n: int = 5;"},
+		{"let n = 5", "let n = 5;"},
+		{"let code: | 200 | 404 = 200;", "let code: | 200 | 404 = 200;"},
+		{"func(x: int, y: int): int { x + y }", "func(x: int, y: int): int(x + y)"},
+		{"func(x, y: bool) { y }", "func(x, y: bool)y"},
+		{"func(): bool { true }", "func(): booltrue"},
+		{"let apply = func(f: func(int): int, x: int): int { f(x) }", "let apply = func(f: func(int): int, x: int): intf(x);"},
+		{"let unit: func(): int = func() { 1 }", "let unit: func(): int = func()1;"},
+	}
+
+	for _, tt := range tests {
+		lxr := scanner.New(tt.input)
+		p := New(lxr)
+		program := p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 0 {
+			t.Errorf("parser had %d errors", len(errors))
+			for _, msg := range errors {
+				t.Errorf("parser error: %q", msg)
+			}
+			t.FailNow()
+		}
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected %q, received %q", tt.expected, actual)
+		}
+	}
+}
+
+func TestFunctionArgumentTypes(t *testing.T) {
+	input := "func(x: int, y, z: bool): int { x }"
+
+	lxr := scanner.New(input)
+	p := New(lxr)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser had errors: %v", p.Errors())
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	function := stmt.Expression.(*ast.FunctionLiteral)
+
+	if len(function.ArgumentTypes) != len(function.Arguments) {
+		t.Fatalf("ArgumentTypes has %d entries, expected %d", len(function.ArgumentTypes), len(function.Arguments))
+	}
+
+	testIdentifier(t, function.ArgumentTypes[0], "int")
+	if function.ArgumentTypes[1] != nil {
+		t.Errorf("y should not have a type, received %s", function.ArgumentTypes[1])
+	}
+	testIdentifier(t, function.ArgumentTypes[2], "bool")
+	testIdentifier(t, function.ReturnType, "int")
+}
+
+func TestReturnStatementForms(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc []string
+	}{
+		{"return 5;", []string{"return 5;"}},
+		{"return 5", []string{"return 5;"}},
+		{"return;", []string{"return ;"}},
+		{"return", []string{"return ;"}},
+		{"return x + 1\nreturn y", []string{"return (x + 1);", "return y;"}},
+		{"func() { return }", []string{"func()return ;"}},
+	}
+
+	for _, tt := range tests {
+		p := New(scanner.New(tt.input))
+		program := p.ParseProgram()
+		if errors := p.Errors(); len(errors) != 0 {
+			t.Errorf("%q: parser had %d errors, first: %s", tt.input, len(errors), errors[0])
+			continue
+		}
+
+		if len(program.Statements) != len(tt.expecc) {
+			t.Errorf("%q: expecc %d statements, received %d", tt.input, len(tt.expecc), len(program.Statements))
+			continue
+		}
+		for i, stmt := range program.Statements {
+			if stmt.String() != tt.expecc[i] {
+				t.Errorf("%q: statement %d, expecc %q, received %q", tt.input, i, tt.expecc[i], stmt.String())
+			}
+		}
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{`"hello world"`, "hello world"},
+		{`""`, ""},
+		{`"tab\there"`, "tab\there"},
+		{`"line\nbreak\r"`, "line\nbreak\r"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"\u{48}\u{69} \u{1F333}"`, "Hi 🌳"},
+		{`"ŐAK"`, "ŐAK"},
+	}
+
+	for _, tt := range tests {
+		p := New(scanner.New(tt.input))
+		program := p.ParseProgram()
+		if errors := p.Errors(); len(errors) != 0 {
+			t.Errorf("%s: parser had %d errors, first: %s", tt.input, len(errors), errors[0])
+			continue
+		}
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		lit, ok := stmt.Expression.(*ast.StringLiteral)
+		if !ok {
+			t.Errorf("%s: expression not *ast.StringLiteral, received %T", tt.input, stmt.Expression)
+			continue
+		}
+		if lit.Value != tt.expecc {
+			t.Errorf("%s: lit.Value not %q, received %q", tt.input, tt.expecc, lit.Value)
+		}
+		if lit.String() != tt.input {
+			t.Errorf("%s: lit.String() doesn't give back the source, received %s", tt.input, lit.String())
+		}
+	}
+}
+
+func TestStringLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc string
+	}{
+		{`"never closed`, "1:1: string literal not terminated"},
+		{`"escaped quote\"`, "1:1: string literal not terminated"},
+		{"let s = \"one\nline\"", "1:9: string literal not terminated"},
+		{`"bad \q escape"`, `1:6: unknown escape sequence \q`},
+		{`"\u1234"`, `1:2: invalid unicode escape, expected \u{...}`},
+		{`"\u{}"`, "1:2: invalid unicode escape, expected 1 to 6 hex digits"},
+		{`"\u{xyz}"`, `1:2: invalid unicode escape, "xyz" is not hexadecimal`},
+		{`"\u{D800}"`, "1:2: invalid unicode escape, D800 is not a valid code point"},
+	}
+
+	for _, tt := range tests {
+		p := New(scanner.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("%q: expecc an error, received none", tt.input)
+			continue
+		}
+		if errors[0].String() != tt.expecc {
+			t.Errorf("%q: expecc %q, received %q", tt.input, tt.expecc, errors[0].String())
+		}
+		if errors[0].Code != CodeInvalidString {
+			t.Errorf("%q: expecc code %s, received %s", tt.input, CodeInvalidString, errors[0].Code)
+		}
+	}
+}
+
+func TestScannerErrorsAreMerged(t *testing.T) {
+	p := New(scanner.New("let x = ;\nlet s = \"caf\xe9\";\nlet y = 1"))
+	p.ParseProgram()
+
+	expected := []string{
+		"1:9: no prefix parse function defined for TokenKind ;",
+		"2:13: invalid UTF-8 encoding",
+	}
+	errors := p.Errors()
+	if len(errors) != len(expected) {
+		t.Fatalf("expected %d errors, received %d: %v", len(expected), len(errors), errors)
+	}
+	for i, msg := range expected {
+		if errors[i].String() != msg {
+			t.Errorf("errors[%d]: expected %q, received %q", i, msg, errors[i].String())
+		}
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	input := `
+// HTTP status codes, from the README
+type InformationalCode =
+    | Continue // 100
+    | SwitchingProtocols // 101
+    | Processing // 102 (WebDAV)
+
+/* the codes themselves,
+   /* nested */ */
+type Continue = 100
+type SwitchingProtocols = 101
+type Processing = 102
+`
+	p := New(scanner.New(input))
+	program := p.ParseProgram()
+	if errors := p.Errors(); len(errors) != 0 {
+		t.Fatalf("parser had %d errors, first: %s", len(errors), errors[0])
+	}
+
+	expected := []string{
+		"type InformationalCode = | Continue | SwitchingProtocols | Processing;",
+		"type Continue = 100;",
+		"type SwitchingProtocols = 101;",
+		"type Processing = 102;",
+	}
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("expected %d statements, received %d", len(expected), len(program.Statements))
+	}
+	for i, stmt := range program.Statements {
+		if stmt.String() != expected[i] {
+			t.Errorf("statement %d: expected %q, received %q", i, expected[i], stmt.String())
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input  string
+		expecc interface{}
+	}{
+		{"1_000_000", int64(1000000)},
+		{"0xFF", int64(255)},
+		{"0Xff", int64(255)},
+		{"0o755", int64(493)},
+		{"0b1010", int64(10)},
+		{"0b_1010", int64(10)},
+		{"0", int64(0)},
+		{"9223372036854775807", int64(9223372036854775807)},
+		{"3.14", 3.14},
+		{"1e3", 1000.0},
+		{"2.5E-3", 0.0025},
+		{"1_000.000_5", 1000.0005},
+	}
+
+	for _, tt := range tests {
+		p := New(scanner.New(tt.input))
+		program := p.ParseProgram()
+		if errors := p.Errors(); len(errors) != 0 {
+			t.Errorf("%s: parser had %d errors, first: %s", tt.input, len(errors), errors[0])
+			continue
+		}
+
+		expr := program.Statements[0].(*ast.ExpressionStatement).Expression
+		switch expecc := tt.expecc.(type) {
+		case int64:
+			lit, ok := expr.(*ast.IntegerLiteral)
+			if !ok || lit.Value != expecc {
+				t.Errorf("%s: expecc integer %d, received %T (%+v)", tt.input, expecc, expr, expr)
+			}
+		case float64:
+			lit, ok := expr.(*ast.FloatLiteral)
+			if !ok || lit.Value != expecc {
+				t.Errorf("%s: expecc float %g, received %T (%+v)", tt.input, expecc, expr, expr)
+			}
+		}
+		if expr.String() != tt.input {
+			t.Errorf("%s: String() doesn't give back the source, received %s", tt.input, expr.String())
+		}
+	}
+}
+
+func TestNumberLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		code   string
+		expecc string
+	}{
+		{"9223372036854775808", CodeOverflow, "1:1: integer literal 9223372036854775808 overflows int"},
+		{"0xFFFFFFFFFFFFFFFFF", CodeOverflow, "1:1: integer literal 0xFFFFFFFFFFFFFFFFF overflows int"},
+		{"1e400", CodeOverflow, "1:1: float literal 1e400 overflows float"},
+		{"0b102", CodeInvalidInteger, "1:1: invalid integer literal 0b102"},
+		{"let x = 12px", CodeInvalidInteger, "1:9: invalid integer literal 12px"},
+		{"0x", CodeInvalidInteger, "1:1: invalid integer literal 0x"},
+		{"1__000", CodeInvalidInteger, "1:1: invalid integer literal 1__000"},
+		{"100_", CodeInvalidInteger, "1:1: invalid integer literal 100_"},
+		{"0755", CodeInvalidInteger, "1:1: invalid integer literal 0755, octal literals are written 0o755"},
+		{"1e", CodeInvalidFloat, "1:1: invalid float literal 1e"},
+		{"1.5_", CodeInvalidFloat, "1:1: invalid float literal 1.5_"},
+	}
+
+	for _, tt := range tests {
+		p := New(scanner.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("%s: expecc 1 error, received %d: %v", tt.input, len(errors), errors)
+			continue
+		}
+		if errors[0].String() != tt.expecc {
+			t.Errorf("%s: expecc %q, received %q", tt.input, tt.expecc, errors[0].String())
+		}
+		if errors[0].Code != tt.code {
+			t.Errorf("%s: expecc code %s, received %s", tt.input, tt.code, errors[0].Code)
+		}
+	}
+}
+
+func TestPipeExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"xs |> f", "f(xs)"},
+		{"xs |> filter(isEven)", "filter(isEven, xs)"},
+		{"xs |> filter(isEven) |> map(double)", "map(double, filter(isEven, xs))"},
+		{"xs |> take(1 + 2)", "take((1 + 2), xs)"},
+		{"a + b |> f", "f((a + b))"},
+		{"a == b |> f", "f((a == b))"},
+		{"f <| x", "f(x)"},
+		{"f <| g <| x", "f(g(x))"},
+		{"f <| x |> g", "f(g(x))"},
+		{"x |> g |> h <| y", "h(g(x), y)"},
+		{"map(double) <| xs", "map(double, xs)"},
+		{"let ys = xs |> map(double);", "let ys = map(double, xs);"},
+		{"xs |> func(x) { x }", "func(x)x(xs)"},
+		{"xs |> (f(a))", "f(a)(xs)"},
+		{"xs |> (f)(a)", "f(a, xs)"},
+		{"xs |> ((f(a)))", "f(a)(xs)"},
+		{"(f(a)) <| xs", "f(a)(xs)"},
+		{"ys |> (xs |> f(a))", "f(a, xs)(ys)"},
+	}
+
+	for _, tt := range tests {
+		p := New(scanner.New(tt.input))
+		program := p.ParseProgram()
+		if errors := p.Errors(); len(errors) != 0 {
+			t.Errorf("%q: parser had %d errors, first: %s", tt.input, len(errors), errors[0])
+			continue
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected %q, received %q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestPipeSpans(t *testing.T) {
+	input := "xs |> filter(isEven)"
+	p := New(scanner.New(input))
+	program := p.ParseProgram()
+
+	expr := program.Statements[0].(*ast.ExpressionStatement).Expression
+	if expr.Pos().Offset != 0 || expr.End().Offset != len(input) {
+		t.Errorf("span of %q wrong, received %d to %d", input, expr.Pos().Offset, expr.End().Offset)
+	}
+
+	// with no ) of their own, these end on the last token
+	for _, input := range []string{"f <| x", "xs |> f", "xs |> (f(a))", "x + y |> (f(a))"} {
+		p = New(scanner.New(input))
+		program = p.ParseProgram()
+
+		call := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.InvocationExpression)
+		if call.Pos().Offset != 0 || call.End().Offset != len(input) {
+			t.Errorf("span of %q wrong, received %d to %d", input, call.Pos().Offset, call.End().Offset)
+		}
+		if call.Rparen.End().Offset != len(input) {
+			t.Errorf("%q: expected Rparen to end at %d, received %d", input, len(input), call.Rparen.End().Offset)
+		}
+	}
+}
+
+func TestRangeExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1..10]", "[1..10]"},
+		{"[0..<n]", "[0..<n]"},
+		{"[1, 3..9]", "[1, 3..9]"},
+		{"[10, 8..<0]", "[10, 8..<0]"},
+		{"[a + 1..b * 2]", "[(a + 1)..(b * 2)]"},
+		{"[-n..n]", "[(-n)..n]"},
+		{"[1..10] |> take(3)", "take(3, [1..10])"},
+	}
+
+	for _, tt := range tests {
+		p := New(scanner.New(tt.input))
+		program := p.ParseProgram()
+		if errors := p.Errors(); len(errors) != 0 {
+			t.Errorf("%q: parser had %d errors, first: %s", tt.input, len(errors), errors[0])
+			continue
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected %q, received %q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	input := "[1, 3..<9]"
+	p := New(scanner.New(input))
+	program := p.ParseProgram()
+	expr, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.RangeExpression)
+	if !ok {
+		t.Fatalf("%q: expecc *ast.RangeExpression, received %T", input, program.Statements[0])
+	}
+	if !expr.Exclusive || expr.Next == nil {
+		t.Errorf("%q: expecc an exclusive range with a step, received %+v", input, expr)
+	}
+	if expr.Pos().Offset != 0 || expr.End().Offset != len(input) {
+		t.Errorf("span of %q wrong, received %d to %d", input, expr.Pos().Offset, expr.End().Offset)
+	}
+
+	for _, input := range []string{"[1..", "[1 10]", "[..10]", "[1..10"} {
+		p := New(scanner.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expecc errors, received none", input)
+		}
+	}
+}
+
+func TestRecordLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{}", "{}"},
+		{"{a: 1}", "{a: 1}"},
+		{"{a: 1, b: x + 2,}", "{a: 1, b: (x + 2)}"},
+		{"{...r, a: 1}", "{...r, a: 1}"},
+		{"{a: {b: c}}", "{a: {b: c}}"},
+		{"{f: func(x) { x }}", "{f: func(x)x}"},
+		{"r.a", "r.a"},
+		{"r.a.b", "r.a.b"},
+		{"-r.a", "(-r.a)"},
+		{"r.a + s.b * 2", "(r.a + (s.b * 2))"},
+		{"f(x).a", "f(x).a"},
+		{"r.f(x)", "r.f(x)"},
+		{"{...r.inner}", "{...r.inner}"},
+		{"{a: 1}.a", "{a: 1}.a"},
+		{"r |> f", "f(r)"},
+		{"if (r.ok) { {a: 1} }", "ifr.ok {a: 1}"},
+	}
+
+	for _, tt := range tests {
+		p := New(scanner.New(tt.input))
+		program := p.ParseProgram()
+		if errors := p.Errors(); len(errors) != 0 {
+			t.Errorf("%q: parser had %d errors, first: %s", tt.input, len(errors), errors[0])
+			continue
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected %q, received %q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	input := "{a: 1, ...r}.a"
+	p := New(scanner.New(input))
+	program := p.ParseProgram()
+	expr := program.Statements[0].(*ast.ExpressionStatement).Expression
+	if expr.Pos().Offset != 0 || expr.End().Offset != len(input) {
+		t.Errorf("span of %q wrong, received %d to %d", input, expr.Pos().Offset, expr.End().Offset)
+	}
+
+	for _, input := range []string{"{a}", "{a: }", "{a: 1 b: 2}", "{a: 1", "r.", "r.1", "{...}"} {
+		p := New(scanner.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expecc errors, received none", input)
+		}
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[]", "[]"},
+		{"[1]", "[1]"},
+		{"[1, 2 * 3, x + 4]", "[1, (2 * 3), (x + 4)]"},
+		{"[1, 2,]", "[1, 2]"},
+		{"[[1], []]", "[[1], []]"},
+		{"[...xs, 4]", "[...xs, 4]"},
+		{"[1, 2..3]", "[1, 2..3]"},
+		{"xs[0]", "xs[0]"},
+		{"xs[i + 1]", "xs[(i + 1)]"},
+		{"xs[0][1]", "xs[0][1]"},
+		{"[1, 2][0]", "[1, 2][0]"},
+		{"a * xs[1] + f(b)[2]", "((a * xs[1]) + f(b)[2])"},
+		{"-xs[0]", "(-xs[0])"},
+		{"r.xs[0].y", "r.xs[0].y"},
+		{"f([1, 2], [3..4])", "f([1, 2], [3..4])"},
+	}
+
+	for _, tt := range tests {
+		p := New(scanner.New(tt.input))
+		program := p.ParseProgram()
+		if errors := p.Errors(); len(errors) != 0 {
+			t.Errorf("%q: parser had %d errors, first: %s", tt.input, len(errors), errors[0])
+			continue
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected %q, received %q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	input := "xs[1]"
+	p := New(scanner.New(input))
+	program := p.ParseProgram()
+	expr := program.Statements[0].(*ast.ExpressionStatement).Expression
+	if _, ok := expr.(*ast.IndexExpression); !ok {
+		t.Fatalf("%q: expecc *ast.IndexExpression, received %T", input, expr)
+	}
+	if expr.Pos().Offset != 0 || expr.End().Offset != len(input) {
+		t.Errorf("span of %q wrong, received %d to %d", input, expr.Pos().Offset, expr.End().Offset)
+	}
+
+	for _, input := range []string{"[1, 2", "[1 2]", "[,]", "xs[]", "xs[1", "[1, 2, 3..4]", "[...xs..4]"} {
+		p := New(scanner.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expecc errors, received none", input)
+		}
+	}
+
+	p = New(scanner.New("[1, 2, 3..4]"))
+	p.ParseProgram()
+	if errors := p.Errors(); len(errors) != 1 || errors[0].String() != "1:8: too many elements before .. in range, want at most 2" {
+		t.Errorf("expecc 1 error about the range bounds, received %v", errors)
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{200: "OK", 404: "Not Found"}`, `{200: "OK", 404: "Not Found"}`},
+		{`{"a": 1, "b": 2,}`, `{"a": 1, "b": 2}`},
+		{"{true: 1, false: 0}", "{true: 1, false: 0}"},
+		{"{1 + 1: x * 2}", "{(1 + 1): (x * 2)}"},
+		{"{(k): v}", "{(k): v}"},
+		{"{(k): v, j: 1}", "{(k): v, (j): 1}"},
+		{"{-1: a}", "{(-1): a}"},
+		{`{"a": {"b": 1}}["a"]["b"]`, `{"a": {"b": 1}}["a"]["b"]`},
+		{"{a: 1}", "{a: 1}"},
+		{"{:}", "{:}"},
+		{"{ : }", "{:}"},
+		{"keys({:})", "keys({:})"},
+	}
+
+	for _, tt := range tests {
+		p := New(scanner.New(tt.input))
+		program := p.ParseProgram()
+		if errors := p.Errors(); len(errors) != 0 {
+			t.Errorf("%q: parser had %d errors, first: %s", tt.input, len(errors), errors[0])
+			continue
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected %q, received %q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	for input, expected := range map[string]string{
+		"{1: 2}":      "*ast.HashLiteral",
+		"{(x): 2}":    "*ast.HashLiteral",
+		"{x: 2}":      "*ast.RecordLiteral",
+		"{...r}":      "*ast.RecordLiteral",
+		"{}":          "*ast.RecordLiteral",
+		"{:}":         "*ast.HashLiteral",
+		`{"x": 1}.x`:  "*ast.MemberExpression",
+		`{"x": 1}[0]`: "*ast.IndexExpression",
+	} {
+		p := New(scanner.New(input))
+		program := p.ParseProgram()
+		expr := program.Statements[0].(*ast.ExpressionStatement).Expression
+		if received := fmt.Sprintf("%T", expr); received != expected {
+			t.Errorf("%q: expecc %s, received %s", input, expected, received)
+		}
+	}
+
+	for _, input := range []string{"{1}", "{1: }", "{1: 2 3: 4}", "{1: 2", "{1: 2, ...r}", "{:", "{: 1}", "{:1: 2}"} {
+		p := New(scanner.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expecc errors, received none", input)
+		}
+	}
+}
+
+func TestSwitchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"switch (x) { 1 => a }", "switch (x) {1 => a}"},
+		{"switch (x) { 1 => a, 2 => b, _ => c, }", "switch (x) {1 => a, 2 => b, _ => c}"},
+		{"switch (code) { Ok => \"ok\", NotFound => \"missing\" }", "switch (code) {Ok => \"ok\", NotFound => \"missing\"}"},
+		{"switch (x) { -1 => a, 1.5 => b, true => c, \"s\" => d }", "switch (x) {(-1) => a, 1.5 => b, true => c, \"s\" => d}"},
+		{"switch (r) { {code: 200, body} => body, {code: c, body: _} => c }", "switch (r) {{code: 200, body} => body, {code: c, body: _} => c}"},
+		{"switch (r) { {inner: {x: 1}} => 1, {} => 0 }", "switch (r) {{inner: {x: 1}} => 1, {} => 0}"},
+		{"switch (a + b) { n => n * 2 }", "switch ((a + b)) {n => (n * 2)}"},
+		{"switch (x) {}", "switch (x) {}"},
+		{"let y = switch (x) { _ => {a: 1} } |> f;", "let y = f(switch (x) {_ => {a: 1}});"},
+	}
+
+	for _, tt := range tests {
+		p := New(scanner.New(tt.input))
+		program := p.ParseProgram()
+		if errors := p.Errors(); len(errors) != 0 {
+			t.Errorf("%q: parser had %d errors, first: %s", tt.input, len(errors), errors[0])
+			continue
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected %q, received %q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	input := "switch (x) { _ => 1 }"
+	p := New(scanner.New(input))
+	program := p.ParseProgram()
+	expr, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.SwitchExpression)
+	if !ok {
+		t.Fatalf("%q: expecc *ast.SwitchExpression, received %T", input, program.Statements[0])
+	}
+	if len(expr.Arms) != 1 || expr.Arms[0].Pattern.String() != "_" {
+		t.Errorf("%q: expecc one wildcard arm, received %v", input, expr.Arms)
+	}
+	if expr.Pos().Offset != 0 || expr.End().Offset != len(input) {
+		t.Errorf("span of %q wrong, received %d to %d", input, expr.Pos().Offset, expr.End().Offset)
+	}
+
+	errors := []struct {
+		input  string
+		code   string
+		expecc string
+	}{
+		{"switch (x) { a + 1 => 2 }", CodeUnexpectedToken, "1:16: expected next token to be '=>', received +"},
+		{"switch (x) { [1] => 2 }", CodeExpectedPattern, "1:14: expected a pattern, received ["},
+		{"switch (x) { -a => 2 }", CodeExpectedPattern, "1:14: expected a pattern, received -"},
+		{"switch (x) { {1: 2} => 2 }", CodeUnexpectedToken, "1:15: expected next token to be 'IDENTITY', received INT"},
+		{"switch (x) { 1 => 2 3 => 4 }", CodeUnexpectedToken, "1:21: expected next token to be ',', received INT"},
+		{"switch x { _ => 1 }", CodeUnexpectedToken, "1:8: expected next token to be '(', received IDENTITY"},
+		{"switch (x) { 1 => }", CodeExpectedExpr, "1:19: no prefix parse function defined for TokenKind }"},
+	}
+
+	for _, tt := range errors {
+		p := New(scanner.New(tt.input))
+		p.ParseProgram()
+		errs := p.Errors()
+		if len(errs) == 0 {
+			t.Errorf("%q: expecc errors, received none", tt.input)
+			continue
+		}
+		if errs[0].String() != tt.expecc || errs[0].Code != tt.code {
+			t.Errorf("%q: expecc %s %q, received %s %q", tt.input, tt.code, tt.expecc, errs[0].Code, errs[0].String())
+		}
+	}
+}
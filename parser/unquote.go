@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// unquoteError says what is wrong with a string
+// literal, and where, as a byte offset and length
+// within it. A zero length marks the whole rest
+type unquoteError struct {
+	offset  int
+	length  int
+	message string
+}
+
+// unquote resolves the escapes of a string literal
+// as the scanner found it, quotes included. These
+// are \n, \t, \r, \", \\ and \u{...}, the latter
+// holding the hex code point of any character
+func unquote(literal string) (string, *unquoteError) {
+	if len(literal) < 2 || literal[len(literal)-1] != '"' || !closed(literal) {
+		return "", &unquoteError{message: "string literal not terminated"}
+	}
+
+	var out strings.Builder
+	body := literal[1 : len(literal)-1]
+	for i := 0; i < len(body); {
+		if body[i] != '\\' {
+			out.WriteByte(body[i])
+			i++
+			continue
+		}
+
+		// offsets are into literal, so step
+		// past the opening quote as well
+		start := i + 1
+		switch body[i+1] {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case '"':
+			out.WriteByte('"')
+		case '\\':
+			out.WriteByte('\\')
+		case 'u':
+			r, width, err := unicodeEscape(body[i:])
+			if err != "" {
+				return "", &unquoteError{offset: start, length: width, message: err}
+			}
+			out.WriteRune(r)
+			i += width
+			continue
+		default:
+			_, width := utf8.DecodeRuneInString(body[i+1:])
+			return "", &unquoteError{offset: start, length: 1 + width, message: "unknown escape sequence \\" + body[i+1:i+1+width]}
+		}
+		i += 2
+	}
+
+	return out.String(), nil
+}
+
+// closed reports whether the final quote of literal
+// closes it, rather than being escaped
+func closed(literal string) bool {
+	backslashes := 0
+	for i := len(literal) - 2; i > 0 && literal[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 0
+}
+
+// unicodeEscape decodes a \u{...} escape at the
+// start of s, giving back its width in bytes
+func unicodeEscape(s string) (rune, int, string) {
+	end := strings.IndexByte(s, '}')
+	if !strings.HasPrefix(s, `\u{`) || end < 0 {
+		return 0, 2, `invalid unicode escape, expected \u{...}`
+	}
+
+	digits := s[3:end]
+	if len(digits) == 0 || len(digits) > 6 {
+		return 0, end + 1, "invalid unicode escape, expected 1 to 6 hex digits"
+	}
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return 0, end + 1, "invalid unicode escape, " + strconv.Quote(digits) + " is not hexadecimal"
+	}
+	if r := rune(value); utf8.ValidRune(r) {
+		return r, end + 1, ""
+	}
+	return 0, end + 1, "invalid unicode escape, " + digits + " is not a valid code point"
+}
@@ -5,15 +5,21 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/SCKelemen/oak/ast"
+	"github.com/SCKelemen/oak/diagnostic"
 	"github.com/SCKelemen/oak/evaluator"
+	"github.com/SCKelemen/oak/object"
 	"github.com/SCKelemen/oak/parser"
 	"github.com/SCKelemen/oak/scanner"
+	"github.com/SCKelemen/oak/types"
 )
 
 const PROMPT = "🌳> "
 
 func Start(in io.Reader, out io.Writer) {
 	scnr := bufio.NewScanner(in)
+	checker := types.New()
+	env := object.NewEnvironment()
 
 	for {
 		fmt.Printf(PROMPT)
@@ -28,11 +34,24 @@ func Start(in io.Reader, out io.Writer) {
 
 		program := p.ParseProgram()
 		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+			diagnostic.RenderAll(out, ln, p.Errors())
 			continue
 		}
 
-		val := evaluator.Eval(program)
+		diags := checker.Check(program)
+		diagnostic.RenderAll(out, ln, diags)
+		if diagnostic.HasErrors(diags) {
+			checker.Rollback()
+			continue
+		}
+		// a line which fails part way binds nothing,
+		// so the checker mustn't remember it either
+		val := evaluator.Eval(program, env)
+		if _, ok := val.(*object.Error); ok {
+			checker.Rollback()
+		} else {
+			printInferredTypes(out, checker, program)
+		}
 		if val != nil {
 			io.WriteString(out, val.Inspect())
 			io.WriteString(out, "\n")
@@ -41,8 +60,16 @@ func Start(in io.Reader, out io.Writer) {
 
 }
 
-func printParserErrors(out io.Writer, errors []string) {
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+// printInferredTypes shows what the checker
+// made of each name the line bound
+func printInferredTypes(out io.Writer, checker *types.Checker, program *ast.Program) {
+	for _, stmt := range program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok {
+			continue
+		}
+		if t, ok := checker.Scope().LookupValue(let.Name.Value); ok {
+			fmt.Fprintf(out, "%s: %s\n", let.Name.Value, t)
+		}
 	}
 }
@@ -1,55 +1,131 @@
 package scanner
 
 import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/oak/diagnostic"
 	"github.com/SCKelemen/oak/token"
 
 	"github.com/SCKelemen/oak/util"
 )
 
+// diagnostic codes reported by the scanner
+const (
+	CodeInvalidUTF8         = "S0001"
+	CodeUnterminatedComment = "S0002"
+)
+
+// Mode controls what the scanner gives back
+// besides the tokens the parser cares about
+type Mode uint
+
+const (
+	// ScanComments emits comments as COMMENT
+	// tokens, rather than skipping over them
+	ScanComments Mode = 1 << iota
+
+	// ScanTrivia emits whitespace, and comments
+	// unless ScanComments is set too, as TRIVIA
+	// tokens. Joining the literals of all the
+	// tokens then gives back the input exactly
+	ScanTrivia
+)
+
 // Scanner is the lexer
 type Scanner struct {
-	input   string
-	head    int
-	read    int
-	current rune
+	filename string
+	input    string
+	head     int
+	read     int
+	current  rune
+	mode     Mode
+
+	// lines holds the byte offset of the
+	// first character of every line seen
+	// so far, lines[0] is always 0
+	lines []int
+
+	errors []diagnostic.Diagnostic
 }
 
 func New(input string) *Scanner {
-	s := &Scanner{input: input}
+	return NewFile("", input)
+}
+
+// NewFile is like New, but stamps every token
+// position with the name of the file it came from
+func NewFile(filename, input string) *Scanner {
+	s := &Scanner{filename: filename, input: input, lines: []int{0}}
 
 	s.readChar()
 	return s
 }
 
+// SetMode changes what the scanner gives back
+// from here on, see Mode
+func (s *Scanner) SetMode(mode Mode) {
+	s.mode = mode
+}
+
+// Errors gives back the problems found in
+// the input so far, such as invalid UTF-8
+func (s *Scanner) Errors() []diagnostic.Diagnostic {
+	return s.errors
+}
+
 // readChar 's only responsibility is to progress
 // the read-ahead head, check for EOF, and then
 // update head to read-ahead head
 func (s *Scanner) readChar() {
+	// set the head to the read-ahead head
+	s.head = s.read
+
 	// if the look-ahead pointer reaches
 	// the end of the input stream,
 	// set the current character to NUL/0
 	// indicating EOF
 	if s.read >= len(s.input) {
 		s.current = 0
-	} else {
-		// else, set the current character
-		// under inspection to be the char
-		// at the look-ahead position
-		s.current = rune(s.input[s.read])
+		return
 	}
 
-	// then we can set the head to the
-	// read-ahead head
-	s.head = s.read
-	// and then increment the read-ahead head
-	s.read++
+	// else, decode the character at the
+	// look-ahead position, which may take
+	// several bytes, and move past it
+	ch, width := utf8.DecodeRuneInString(s.input[s.read:])
+	if ch == utf8.RuneError && width == 1 {
+		s.errors = append(s.errors, diagnostic.Diagnostic{
+			Severity: diagnostic.Error,
+			Span:     s.span(s.head, s.head+1),
+			Code:     CodeInvalidUTF8,
+			Message:  "invalid UTF-8 encoding",
+		})
+	}
+	s.current = ch
+	s.read += width
+
+	// keep track of where lines begin, so that
+	// offsets can be turned into line:column
+	if ch == '\n' {
+		s.lines = append(s.lines, s.read)
+	}
 }
 
 // NextToken emits the next token. Handles single
 //char tokens internally, directly
 func (s *Scanner) NextToken() token.Token {
 	var tok token.Token
+
+	if s.mode&ScanTrivia != 0 && util.IsWhitespace(s.current) {
+		start := s.head
+		s.skipWhitespace()
+		return token.Token{TokenKind: token.TRIVIA, Literal: s.input[start:s.head], Span: s.span(start, s.head)}
+	}
+
 	s.skipWhitespace()
+	start := s.head
 
 	switch s.current {
 	/*
@@ -84,15 +160,35 @@ func (s *Scanner) NextToken() token.Token {
 	case ')':
 		tok = newToken(token.RPAREN, s.current)
 	case '<':
-		tok = newToken(token.LCHEV, s.current)
+		switch s.peekChar() {
+		case '=':
+			tok = s.pair(token.LEQL)
+		case '|':
+			tok = s.pair(token.RPIPE)
+		default:
+			tok = newToken(token.LCHEV, s.current)
+		}
 	case '>':
-		tok = newToken(token.RCHEV, s.current)
+		if s.peekChar() == '=' {
+			tok = s.pair(token.GEQL)
+		} else {
+			tok = newToken(token.RCHEV, s.current)
+		}
 
 	// handle punctionationy things
 	case ',':
 		tok = newToken(token.COMMA, s.current)
 	case '.':
-		tok = newToken(token.DOT, s.current)
+		switch {
+		case strings.HasPrefix(s.input[s.head:], "..."):
+			s.readChar()
+			s.readChar()
+			tok = token.Token{TokenKind: token.SPREAD, Literal: "..."}
+		case s.peekChar() == '.':
+			tok = s.pair(token.RANGE)
+		default:
+			tok = newToken(token.DOT, s.current)
+		}
 	case ':':
 		tok = newToken(token.COLON, s.current)
 	case ';':
@@ -100,26 +196,34 @@ func (s *Scanner) NextToken() token.Token {
 
 	// handle arithmeticy things
 	case '=':
-		if s.peekChar() == '=' {
-			ch := s.current
-			s.readChar()
-			literal := string(ch) + string(s.current)
-			tok = token.Token{TokenKind: token.EQL, Literal: literal}
-		} else {
+		switch s.peekChar() {
+		case '=':
+			tok = s.pair(token.EQL)
+		case '>':
+			tok = s.pair(token.ARROW)
+		default:
 			tok = newToken(token.ASSIGN, s.current)
 		}
 	// handle bitwise/type like things
 	case '|':
-		tok = newToken(token.PIPE, s.current)
+		switch s.peekChar() {
+		case '|':
+			tok = s.pair(token.LOR)
+		case '>':
+			tok = s.pair(token.FPIPE)
+		default:
+			tok = newToken(token.PIPE, s.current)
+		}
 	case '&':
-		tok = newToken(token.AMP, s.current)
+		if s.peekChar() == '&' {
+			tok = s.pair(token.LAND)
+		} else {
+			tok = newToken(token.AMP, s.current)
+		}
 
 	case '!':
 		if s.peekChar() == '=' {
-			ch := s.current
-			s.readChar()
-			literal := string(ch) + string(s.current)
-			tok = token.Token{TokenKind: token.NEQL, Literal: literal}
+			tok = s.pair(token.NEQL)
 		} else {
 			tok = newToken(token.BANG, s.current)
 		}
@@ -130,28 +234,85 @@ func (s *Scanner) NextToken() token.Token {
 	case '*':
 		tok = newToken(token.MUL, s.current)
 	case '/':
-		tok = newToken(token.QUO, s.current)
+		switch s.peekChar() {
+		case '/', '*':
+			tok.Literal = s.readComment()
+			tok.TokenKind = token.COMMENT
+			tok.Span = s.span(start, s.head)
+			switch {
+			case s.mode&ScanComments != 0:
+			case s.mode&ScanTrivia != 0:
+				tok.TokenKind = token.TRIVIA
+			default:
+				return s.NextToken()
+			}
+			return tok
+		default:
+			tok = newToken(token.QUO, s.current)
+		}
+
+	case '"':
+		tok.Literal = s.readString()
+		tok.TokenKind = token.STRING
+		tok.Span = s.span(start, s.head)
+		return tok
 
-	// handle the nul/eof char
+	// handle the nul/eof char, a NUL in
+	// the input itself is no end of file
 	case 0:
-		tok.Literal = ""
-		tok.TokenKind = token.EOF
+		if s.atEOF() {
+			tok.Literal = ""
+			tok.TokenKind = token.EOF
+		} else {
+			tok = newToken(token.ILLEGAL, s.current)
+		}
 
 	default:
-		if util.IsLetter(s.current) {
+		// words and numbers leave us on the character
+		// after them already, so return straight away
+		if util.IsIdentifierInitialChar(s.current) {
 			tok.Literal = s.readWord()
 			tok.TokenKind = token.Lookup(tok.Literal)
-		} else if util.IsDigit(s.current) {
-			tok.Literal = s.readNumber()
-			tok.TokenKind = token.INT
+			tok.Span = s.span(start, s.head)
+			return tok
+		} else if util.IsNumericInitialChar(s.current) {
+			tok.Literal, tok.TokenKind = s.readNumber()
+			tok.Span = s.span(start, s.head)
+			return tok
+		} else if s.current == utf8.RuneError {
+			// already reported, keep the raw
+			// bytes rather than U+FFFD
+			tok.Literal = s.input[s.head:s.read]
+			tok.TokenKind = token.ILLEGAL
 		} else {
 			tok = newToken(token.ILLEGAL, s.current)
 		}
 	}
 	s.readChar()
+	tok.Span = s.span(start, s.head)
 	return tok
 }
 
+// Position converts a byte offset into
+// the input to a full source position
+func (s *Scanner) Position(offset int) token.Position {
+	if offset > len(s.input) {
+		offset = len(s.input)
+	}
+	// find the last line starting at or before offset
+	line := sort.Search(len(s.lines), func(i int) bool { return s.lines[i] > offset }) - 1
+	return token.Position{
+		Filename: s.filename,
+		Offset:   offset,
+		Line:     line + 1,
+		Column:   offset - s.lines[line] + 1,
+	}
+}
+
+func (s *Scanner) span(start, end int) token.Span {
+	return token.Span{Start: s.Position(start), End: s.Position(end)}
+}
+
 // skipWhitespace 's only responsibility is to
 // read while the current token under inspection
 // remains a whitespace character. These don't have
@@ -166,29 +327,141 @@ func newToken(kind token.TokenKind, ch rune) token.Token {
 	return token.Token{TokenKind: kind, Literal: string(ch)}
 }
 
-// read until the next space
+// pair makes a two char token of the current
+// char and the next, reading past the first
+func (s *Scanner) pair(kind token.TokenKind) token.Token {
+	ch := s.current
+	s.readChar()
+	return token.Token{TokenKind: kind, Literal: string(ch) + string(s.current)}
+}
+
+// read until the end of the identifier
 func (s *Scanner) readWord() string {
 	position := s.head
 	for util.IsIdentifierChar(s.current) {
 		s.readChar()
 	}
-	s.read--
 	return s.input[position:s.head]
 }
 
-func (s *Scanner) readNumber() string {
+// readNumber reads an integer, which may be written
+// in hex, octal or binary with a 0x, 0o or 0b prefix,
+// or a decimal float with a fraction, an exponent or
+// both. Digits may be separated by underscores. The
+// parser checks the literal is actually valid
+func (s *Scanner) readNumber() (string, token.TokenKind) {
 	position := s.head
-	for util.IsDigit(s.current) {
+	kind := token.INT
+
+	if s.current == '0' && strings.ContainsRune("xXoObB", s.peekChar()) {
+		s.readChar()
+		s.readChar()
+	} else {
+		s.readDigits()
+
+		// 1..10 is a range, not a float
+		if s.current == '.' && util.IsDigit(s.peekChar()) {
+			kind = token.FLOAT
+			s.readChar()
+			s.readDigits()
+		}
+		if s.current == 'e' || s.current == 'E' {
+			kind = token.FLOAT
+			s.readChar()
+			if s.current == '+' || s.current == '-' {
+				s.readChar()
+			}
+		}
+	}
+
+	// take anything running on from the number along,
+	// so 0b102 or 12px are reported as one bad literal
+	for util.IsIdentifierChar(s.current) {
+		s.readChar()
+	}
+	return s.input[position:s.head], kind
+}
+
+func (s *Scanner) readDigits() {
+	for util.IsNumericChar(s.current) {
+		s.readChar()
+	}
+}
+
+// readString reads a string literal, quotes and all,
+// leaving the escapes for the parser to deal with.
+// Strings end at a newline or EOF if they're never
+// closed, which the parser will complain about
+func (s *Scanner) readString() string {
+	position := s.head
+	for {
+		s.readChar()
+		if s.current == '\\' {
+			s.readChar()
+			if !s.atEOF() && s.current != '\n' {
+				continue
+			}
+		}
+		if s.current == '"' || s.current == '\n' || s.atEOF() {
+			break
+		}
+	}
+
+	// step past the closing quote, a newline
+	// is left to be skipped as whitespace
+	if s.current == '"' {
+		s.readChar()
+	}
+	return s.input[position:s.head]
+}
+
+// readComment reads a // comment up to the end of
+// the line, or a /* */ comment, which may nest, up
+// to its closing */
+func (s *Scanner) readComment() string {
+	position := s.head
+
+	s.readChar()
+	if s.current == '/' {
+		for s.current != '\n' && !s.atEOF() {
+			s.readChar()
+		}
+		return s.input[position:s.head]
+	}
+
+	depth := 1
+	s.readChar()
+	for depth > 0 {
+		switch {
+		case s.atEOF():
+			s.errors = append(s.errors, diagnostic.Diagnostic{
+				Severity: diagnostic.Error,
+				Span:     s.span(position, position+2),
+				Code:     CodeUnterminatedComment,
+				Message:  "comment not terminated",
+			})
+			return s.input[position:s.head]
+		case s.current == '/' && s.peekChar() == '*':
+			depth++
+			s.readChar()
+		case s.current == '*' && s.peekChar() == '/':
+			depth--
+			s.readChar()
+		}
 		s.readChar()
 	}
-	s.read--
 	return s.input[position:s.head]
 }
 
-func (s *Scanner) peekChar() byte {
+// atEOF reports whether the whole input has been read
+func (s *Scanner) atEOF() bool {
+	return s.head >= len(s.input)
+}
+
+func (s *Scanner) peekChar() rune {
 	if s.read >= len(s.input) {
 		return 0
-	} else {
-		return s.input[s.read]
 	}
+	ch, _ := utf8.DecodeRuneInString(s.input[s.read:])
+	return ch
 }
@@ -1,6 +1,7 @@
 package scanner
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/SCKelemen/oak/token"
@@ -160,3 +161,405 @@ func TestScanMultiChars(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x == 10\n"
+	tests := []struct {
+		expectedKind  token.TokenKind
+		expectedStart token.Position
+		expectedEnd   token.Position
+	}{
+		{token.LET, token.Position{Offset: 0, Line: 1, Column: 1}, token.Position{Offset: 3, Line: 1, Column: 4}},
+		{token.IDENT, token.Position{Offset: 4, Line: 1, Column: 5}, token.Position{Offset: 5, Line: 1, Column: 6}},
+		{token.ASSIGN, token.Position{Offset: 6, Line: 1, Column: 7}, token.Position{Offset: 7, Line: 1, Column: 8}},
+		{token.INT, token.Position{Offset: 8, Line: 1, Column: 9}, token.Position{Offset: 9, Line: 1, Column: 10}},
+		{token.SEMI, token.Position{Offset: 9, Line: 1, Column: 10}, token.Position{Offset: 10, Line: 1, Column: 11}},
+		{token.IDENT, token.Position{Offset: 13, Line: 2, Column: 3}, token.Position{Offset: 14, Line: 2, Column: 4}},
+		{token.EQL, token.Position{Offset: 15, Line: 2, Column: 5}, token.Position{Offset: 17, Line: 2, Column: 7}},
+		{token.INT, token.Position{Offset: 18, Line: 2, Column: 8}, token.Position{Offset: 20, Line: 2, Column: 10}},
+		{token.EOF, token.Position{Offset: 21, Line: 3, Column: 1}, token.Position{Offset: 21, Line: 3, Column: 1}},
+	}
+
+	scnr := New(input)
+	for i, tt := range tests {
+		tok := scnr.NextToken()
+		if tok.TokenKind != tt.expectedKind {
+			t.Fatalf("tests[%d] - tokenKind wrong. expected=%q, got=%q",
+				i, tt.expectedKind, tok.TokenKind)
+		}
+
+		if tok.Pos() != tt.expectedStart {
+			t.Fatalf("tests[%d] - start wrong. expected=%+v, got=%+v",
+				i, tt.expectedStart, tok.Pos())
+		}
+
+		if tok.End() != tt.expectedEnd {
+			t.Fatalf("tests[%d] - end wrong. expected=%+v, got=%+v",
+				i, tt.expectedEnd, tok.End())
+		}
+	}
+}
+
+func TestTokenFilename(t *testing.T) {
+	scnr := NewFile("lexer.oak", "\n\ttype")
+	tok := scnr.NextToken()
+
+	if tok.Pos().String() != "lexer.oak:2:2" {
+		t.Fatalf("position wrong. expected=%q, got=%q", "lexer.oak:2:2", tok.Pos().String())
+	}
+}
+
+func TestScanStrings(t *testing.T) {
+	input := `"foobar" "foo bar" "" "say \"hi\"" "a\\" + "unterminated` + "\n" + `"\u{1F333}"`
+	tests := []struct {
+		expectedKind    token.TokenKind
+		expectedLiteral string
+	}{
+		{token.STRING, `"foobar"`},
+		{token.STRING, `"foo bar"`},
+		{token.STRING, `""`},
+		{token.STRING, `"say \"hi\""`},
+		{token.STRING, `"a\\"`},
+		{token.SUM, "+"},
+		{token.STRING, `"unterminated`},
+		{token.STRING, `"\u{1F333}"`},
+		{token.EOF, ""},
+	}
+
+	scnr := New(input)
+	for i, tt := range tests {
+		tok := scnr.NextToken()
+		if tok.TokenKind != tt.expectedKind {
+			t.Fatalf("tests[%d] - tokenKind wrong. expected=%q, got=%q",
+				i, tt.expectedKind, tok.TokenKind)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestScanUnicode(t *testing.T) {
+	input := "let café = \"ŐAK 🌳\"; 変数 + _x1 × π"
+	tests := []struct {
+		expectedKind    token.TokenKind
+		expectedLiteral string
+		expectedOffset  int
+	}{
+		{token.LET, "let", 0},
+		{token.IDENT, "café", 4},
+		{token.ASSIGN, "=", 10},
+		{token.STRING, "\"ŐAK 🌳\"", 12},
+		{token.SEMI, ";", 23},
+		{token.IDENT, "変数", 25},
+		{token.SUM, "+", 32},
+		{token.IDENT, "_x1", 34},
+		{token.ILLEGAL, "×", 38},
+		{token.IDENT, "π", 41},
+		{token.EOF, "", 43},
+	}
+
+	scnr := New(input)
+	for i, tt := range tests {
+		tok := scnr.NextToken()
+		if tok.TokenKind != tt.expectedKind {
+			t.Fatalf("tests[%d] - tokenKind wrong. expected=%q, got=%q",
+				i, tt.expectedKind, tok.TokenKind)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos().Offset != tt.expectedOffset {
+			t.Fatalf("tests[%d] - offset wrong. expected=%d, got=%d",
+				i, tt.expectedOffset, tok.Pos().Offset)
+		}
+	}
+
+	if errors := scnr.Errors(); len(errors) != 0 {
+		t.Fatalf("valid UTF-8 gave errors: %v", errors)
+	}
+}
+
+func TestScanInvalidUTF8(t *testing.T) {
+	input := "let a\xff = \"b\xfe\"\n\xc3"
+	scnr := New(input)
+
+	kinds := []token.TokenKind{}
+	for tok := scnr.NextToken(); tok.TokenKind != token.EOF; tok = scnr.NextToken() {
+		kinds = append(kinds, tok.TokenKind)
+	}
+
+	expectedKinds := []token.TokenKind{token.LET, token.IDENT, token.ILLEGAL, token.ASSIGN, token.STRING, token.ILLEGAL}
+	if len(kinds) != len(expectedKinds) {
+		t.Fatalf("wrong tokens. expected=%v, got=%v", expectedKinds, kinds)
+	}
+	for i := range kinds {
+		if kinds[i] != expectedKinds[i] {
+			t.Fatalf("wrong tokens. expected=%v, got=%v", expectedKinds, kinds)
+		}
+	}
+
+	expected := []string{
+		"1:6: invalid UTF-8 encoding",
+		"1:12: invalid UTF-8 encoding",
+		"2:1: invalid UTF-8 encoding",
+	}
+	errors := scnr.Errors()
+	if len(errors) != len(expected) {
+		t.Fatalf("expected %d errors, got %d: %v", len(expected), len(errors), errors)
+	}
+	for i, msg := range expected {
+		if errors[i].String() != msg {
+			t.Errorf("errors[%d] wrong. expected=%q, got=%q", i, msg, errors[i].String())
+		}
+		if errors[i].Code != CodeInvalidUTF8 {
+			t.Errorf("errors[%d] code wrong. expected=%s, got=%s", i, CodeInvalidUTF8, errors[i].Code)
+		}
+	}
+}
+
+func TestScanComments(t *testing.T) {
+	input := `a // to the end
+/* block */ b / c /* outer /* inner */ still outer */
+// last`
+	tests := []struct {
+		expectedKind    token.TokenKind
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.COMMENT, "// to the end"},
+		{token.COMMENT, "/* block */"},
+		{token.IDENT, "b"},
+		{token.QUO, "/"},
+		{token.IDENT, "c"},
+		{token.COMMENT, "/* outer /* inner */ still outer */"},
+		{token.COMMENT, "// last"},
+		{token.EOF, ""},
+	}
+
+	scnr := New(input)
+	scnr.SetMode(ScanComments)
+	for i, tt := range tests {
+		tok := scnr.NextToken()
+		if tok.TokenKind != tt.expectedKind {
+			t.Fatalf("tests[%d] - tokenKind wrong. expected=%q, got=%q",
+				i, tt.expectedKind, tok.TokenKind)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	// by default they're skipped
+	scnr = New(input)
+	for _, expected := range []string{"a", "b", "/", "c", ""} {
+		if tok := scnr.NextToken(); tok.Literal != expected {
+			t.Fatalf("comments were not skipped. expected=%q, got=%q", expected, tok.Literal)
+		}
+	}
+}
+
+func TestScanUnterminatedComment(t *testing.T) {
+	scnr := New("a /* never /* closed */")
+	scnr.SetMode(ScanComments)
+
+	scnr.NextToken()
+	tok := scnr.NextToken()
+	if tok.TokenKind != token.COMMENT || tok.Literal != "/* never /* closed */" {
+		t.Fatalf("wrong token. got=%q %q", tok.TokenKind, tok.Literal)
+	}
+	if tok := scnr.NextToken(); tok.TokenKind != token.EOF {
+		t.Fatalf("expected EOF, got=%q", tok.TokenKind)
+	}
+
+	errors := scnr.Errors()
+	if len(errors) != 1 || errors[0].String() != "1:3: comment not terminated" || errors[0].Code != CodeUnterminatedComment {
+		t.Fatalf("expected one unterminated comment error, got %v", errors)
+	}
+}
+
+func TestScanTrivia(t *testing.T) {
+	input := "let x = 5; // five\n\t/* about\n   y */ y\r\n"
+	tests := []struct {
+		expectedKind    token.TokenKind
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.TRIVIA, " "},
+		{token.IDENT, "x"},
+		{token.TRIVIA, " "},
+		{token.ASSIGN, "="},
+		{token.TRIVIA, " "},
+		{token.INT, "5"},
+		{token.SEMI, ";"},
+		{token.TRIVIA, " "},
+		{token.TRIVIA, "// five"},
+		{token.TRIVIA, "\n\t"},
+		{token.TRIVIA, "/* about\n   y */"},
+		{token.TRIVIA, " "},
+		{token.IDENT, "y"},
+		{token.TRIVIA, "\r\n"},
+		{token.EOF, ""},
+	}
+
+	scnr := New(input)
+	scnr.SetMode(ScanTrivia)
+	for i, tt := range tests {
+		tok := scnr.NextToken()
+		if tok.TokenKind != tt.expectedKind {
+			t.Fatalf("tests[%d] - tokenKind wrong. expected=%q, got=%q",
+				i, tt.expectedKind, tok.TokenKind)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	// comments stay comments if asked for
+	scnr = New("a // b\n")
+	scnr.SetMode(ScanTrivia | ScanComments)
+	kinds := []token.TokenKind{token.IDENT, token.TRIVIA, token.COMMENT, token.TRIVIA, token.EOF}
+	for i, kind := range kinds {
+		if tok := scnr.NextToken(); tok.TokenKind != kind {
+			t.Fatalf("kinds[%d] wrong. expected=%q, got=%q", i, kind, tok.TokenKind)
+		}
+	}
+}
+
+func TestTriviaRoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		"   ",
+		"let x = 5;\n",
+		"Lexer: type \n  = input:    string \n  & current:  char \n",
+		"type StatusCode = \n    | InformationalCode // 1xx\n    | SuccessCode\n\n",
+		"/* outer /* inner */ */ func(a, b) { a + b }(1, 2)",
+		"let s = \"say \\\"hi\\\"\\n\"; \"unterminated\nnext",
+		"let café = \"🌳\" /* never closed",
+		"bad \xff bytes \xc3 and a \x00 nul",
+		"\ufeffbom, tabs\tand\r\nline endings // at the end",
+		"!= == ! = <> [] {} () , . : ; | & - + * /",
+	}
+
+	for _, input := range inputs {
+		scnr := New(input)
+		scnr.SetMode(ScanTrivia)
+
+		var out strings.Builder
+		previous := 0
+		for tok := scnr.NextToken(); ; tok = scnr.NextToken() {
+			if tok.Pos().Offset != previous {
+				t.Errorf("%q: token %q starts at %d, expected %d", input, tok.Literal, tok.Pos().Offset, previous)
+			}
+			previous = tok.End().Offset
+
+			out.WriteString(tok.Literal)
+			if tok.TokenKind == token.EOF {
+				break
+			}
+		}
+
+		if out.String() != input {
+			t.Errorf("round trip failed.\nexpected=%q\ngot=     %q", input, out.String())
+		}
+	}
+}
+
+func TestScanNumbers(t *testing.T) {
+	input := "1_000_000 0xFF 0o755 0b1010 3.14 1e10 2.5E-3 6e+2 1..10 0b102 12px 1."
+	tests := []struct {
+		expectedKind    token.TokenKind
+		expectedLiteral string
+	}{
+		{token.INT, "1_000_000"},
+		{token.INT, "0xFF"},
+		{token.INT, "0o755"},
+		{token.INT, "0b1010"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e10"},
+		{token.FLOAT, "2.5E-3"},
+		{token.FLOAT, "6e+2"},
+		{token.INT, "1"},
+		{token.RANGE, ".."},
+		{token.INT, "10"},
+		{token.INT, "0b102"},
+		{token.INT, "12px"},
+		{token.INT, "1"},
+		{token.DOT, "."},
+		{token.EOF, ""},
+	}
+
+	scnr := New(input)
+	for i, tt := range tests {
+		tok := scnr.NextToken()
+		if tok.TokenKind != tt.expectedKind {
+			t.Fatalf("tests[%d] - tokenKind wrong. expected=%q, got=%q",
+				i, tt.expectedKind, tok.TokenKind)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestScanOperators(t *testing.T) {
+	input := "<= >= && || |> <| .. ... < > & | . a...b 1..<2 ||> => == = ==>"
+	tests := []struct {
+		expectedKind    token.TokenKind
+		expectedLiteral string
+	}{
+		{token.LEQL, "<="},
+		{token.GEQL, ">="},
+		{token.LAND, "&&"},
+		{token.LOR, "||"},
+		{token.FPIPE, "|>"},
+		{token.RPIPE, "<|"},
+		{token.RANGE, ".."},
+		{token.SPREAD, "..."},
+		{token.LCHEV, "<"},
+		{token.RCHEV, ">"},
+		{token.AMP, "&"},
+		{token.PIPE, "|"},
+		{token.DOT, "."},
+		{token.IDENT, "a"},
+		{token.SPREAD, "..."},
+		{token.IDENT, "b"},
+		{token.INT, "1"},
+		{token.RANGE, ".."},
+		{token.LCHEV, "<"},
+		{token.INT, "2"},
+		{token.LOR, "||"},
+		{token.RCHEV, ">"},
+		{token.ARROW, "=>"},
+		{token.EQL, "=="},
+		{token.ASSIGN, "="},
+		{token.EQL, "=="},
+		{token.RCHEV, ">"},
+		{token.EOF, ""},
+	}
+
+	scnr := New(input)
+	for i, tt := range tests {
+		tok := scnr.NextToken()
+		if tok.TokenKind != tt.expectedKind {
+			t.Fatalf("tests[%d] - tokenKind wrong. expected=%q, got=%q",
+				i, tt.expectedKind, tok.TokenKind)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package token
+
+import "fmt"
+
+// Position describes a location in the source.
+// Offset is the byte offset from the start of
+// the input, Line and Column both start at 1.
+// Like Offset, Column counts bytes rather than
+// characters. A zero Line means the position
+// is unknown.
+type Position struct {
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
+}
+
+// IsValid reports whether the position is known
+func (pos Position) IsValid() bool { return pos.Line > 0 }
+
+// String returns the position in the familiar
+// file:line:column form, dropping the parts we
+// don't know about
+func (pos Position) String() string {
+	s := pos.Filename
+	if pos.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
+// Span is the half-open range [Start, End)
+// of source covered by a token or a node
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func (span Span) IsValid() bool { return span.Start.IsValid() }
+
+func (span Span) String() string {
+	return span.Start.String()
+}
@@ -7,8 +7,12 @@ type TokenKind int
 type Token struct {
 	TokenKind TokenKind
 	Literal   string
+	Span      Span // where in the source the token was found
 }
 
+func (t Token) Pos() Position { return t.Span.Start }
+func (t Token) End() Position { return t.Span.End }
+
 const (
 	ILLEGAL TokenKind = iota
 	EOF
@@ -16,7 +20,9 @@ const (
 	COMMENT
 
 	IDENT
-	INT // for natural numbers
+	INT    // for natural numbers
+	FLOAT  // 1.5, 1e3
+	STRING // "text", the literal keeps quotes and escapes
 
 	LBRACK // [
 	RBRACK // ]
@@ -46,6 +52,17 @@ const (
 
 	EQL  // ==
 	NEQL // !=
+	LEQL // <=
+	GEQL // >=
+
+	LAND // &&
+	LOR  // ||
+
+	FPIPE  // |>
+	RPIPE  // <|
+	RANGE  // ..
+	SPREAD // ...
+	ARROW  // =>
 
 	_keywords_beg
 	TYPE
@@ -68,6 +85,9 @@ var tokens = [...]string{
 
 	IDENT: "IDENTITY",
 	INT:   "INT",
+	FLOAT: "FLOAT",
+
+	STRING: "STRING",
 
 	LBRACK: "[",
 	RBRACK: "]",
@@ -96,6 +116,17 @@ var tokens = [...]string{
 
 	EQL:  "==",
 	NEQL: "!=",
+	LEQL: "<=",
+	GEQL: ">=",
+
+	LAND: "&&",
+	LOR:  "||",
+
+	FPIPE:  "|>",
+	RPIPE:  "<|",
+	RANGE:  "..",
+	SPREAD: "...",
+	ARROW:  "=>",
 
 	TYPE:   "type",
 	SWITCH: "switch",
@@ -0,0 +1,1240 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/SCKelemen/oak/ast"
+	"github.com/SCKelemen/oak/diagnostic"
+	"github.com/SCKelemen/oak/token"
+)
+
+// diagnostic codes reported by the checker
+const (
+	CodeMismatchedTypes = "T0001"
+	CodeUndefined       = "T0002"
+	CodeArgumentCount   = "T0003"
+	CodeNotCallable     = "T0004"
+	CodeInvalidOperand  = "T0005"
+	CodeUndefinedType   = "T0006"
+	CodeRedeclaredType  = "T0007"
+	CodeNonBoolean      = "T0008"
+	CodeNotAssignable   = "T0009"
+	CodeRecursiveType   = "T0010"
+	CodeEmptyType       = "T0011"
+	CodeDuplicateMember = "T0012"
+	CodeUnreachable     = "T0013"
+)
+
+// Checker walks a program before it is run, resolving
+// the types it declares and making sure expressions
+// use values the way their types allow. Declarations
+// are remembered between calls to Check, so a REPL
+// can check one line at a time
+type Checker struct {
+	scope  *Scope
+	errors []diagnostic.Diagnostic
+
+	// Types records the type of every expression checked
+	Types map[ast.Expression]Type
+
+	// the function literal being checked, if any
+	fn *function
+
+	nextVar int // for naming type variables
+	level   int // how many let bindings deep we are
+
+	// the top level scope as it was before
+	// the last call to Check, for Rollback
+	saved *Scope
+}
+
+type function struct {
+	results []Type
+	returns []ast.Node // where each result came from
+}
+
+func New() *Checker {
+	return &Checker{
+		scope: NewScope(Universe),
+		Types: map[ast.Expression]Type{},
+	}
+}
+
+// Errors returns everything found by the last
+// call to Check, including warnings
+func (c *Checker) Errors() []diagnostic.Diagnostic {
+	return c.errors
+}
+
+// Scope is the top level scope programs are checked in
+func (c *Checker) Scope() *Scope {
+	return c.scope
+}
+
+func (c *Checker) Check(program *ast.Program) []diagnostic.Diagnostic {
+	c.errors = []diagnostic.Diagnostic{}
+
+	// spans into earlier programs mean nothing
+	// next to this one, so stop pointing at them
+	for name := range c.scope.typeDecls {
+		c.scope.typeDecls[name] = nil
+	}
+	c.saved = c.scope.copy()
+
+	c.declareTypes(program.Statements)
+	for _, stmt := range program.Statements {
+		c.statement(stmt)
+	}
+
+	return c.errors
+}
+
+// Rollback forgets whatever the last call to Check
+// declared, as a REPL does with a line it rejects
+// rather than runs
+func (c *Checker) Rollback() {
+	if c.saved != nil {
+		*c.scope = *c.saved
+	}
+}
+
+// declareTypes resolves every type declaration in
+// stmts up front, so that types may refer to those
+// declared further down
+func (c *Checker) declareTypes(stmts []ast.Statement) {
+	decls := []*ast.TypeDeclarationStatement{}
+	for _, stmt := range stmts {
+		decl, ok := stmt.(*ast.TypeDeclarationStatement)
+		if !ok {
+			continue
+		}
+
+		name := decl.Name.Value
+		if prev, ok := c.scope.typeDecls[name]; ok {
+			c.report(diagnostic.Diagnostic{
+				Severity: diagnostic.Error,
+				Span:     spanOf(decl.Name),
+				Code:     CodeRedeclaredType,
+				Message:  fmt.Sprintf("type %s redeclared", name),
+				Notes:    []diagnostic.Note{previously(prev)},
+			})
+			continue
+		}
+
+		c.scope.DeclareType(name, &Named{Name: name})
+		c.scope.typeDecls[name] = decl.Name
+		decls = append(decls, decl)
+	}
+
+	for _, decl := range decls {
+		named := c.scope.types[decl.Name.Value].(*Named)
+		named.Underlying = c.typeExpr(decl.Value)
+	}
+
+	// find every cycle before breaking any of them
+	recursive := []*Named{}
+	for _, decl := range decls {
+		named := c.scope.types[decl.Name.Value].(*Named)
+		if c.isRecursive(named, named.Underlying, map[*Named]bool{}) {
+			c.errorf(decl.Name, CodeRecursiveType, "invalid recursive type %s", named.Name)
+			recursive = append(recursive, named)
+		}
+	}
+	for _, named := range recursive {
+		named.Underlying = Unknown
+	}
+}
+
+// previously points back at where a
+// redeclared type was first declared
+func previously(prev ast.Node) diagnostic.Note {
+	if prev == nil {
+		return diagnostic.Note{Message: "previously declared by an earlier input"}
+	}
+	return diagnostic.Note{Span: spanOf(prev), Message: "previously declared here"}
+}
+
+// isRecursive reports whether t refers back to named
+// without going through a property first. Recursion
+// through properties is fine, it is how lists and
+// trees are built, but type A = | A | int is not
+func (c *Checker) isRecursive(named *Named, t Type, seen map[*Named]bool) bool {
+	switch t := t.(type) {
+	case *Named:
+		if t == named {
+			return true
+		}
+		if seen[t] {
+			return false
+		}
+		seen[t] = true
+		return c.isRecursive(named, t.Underlying, seen)
+	case *Union:
+		for _, v := range t.Variants {
+			if c.isRecursive(named, v, seen) {
+				return true
+			}
+		}
+	case *Intersection:
+		for _, member := range t.Types {
+			if c.isRecursive(named, member, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeExpr resolves the type written as expr
+func (c *Checker) typeExpr(expr ast.Expression) Type {
+	switch expr := expr.(type) {
+
+	case *ast.Identifier:
+		if t, ok := c.scope.LookupType(expr.Value); ok {
+			return t
+		}
+		c.errorf(expr, CodeUndefinedType, "undefined type: %s", expr.Value)
+		return Unknown
+
+	case *ast.LiteralType:
+		switch value := expr.Value.(type) {
+		case *ast.IntegerLiteral:
+			return &Literal{Value: value.Value, Base: Int}
+		case *ast.Boolean:
+			return &Literal{Value: value.Value, Base: Bool}
+		}
+		return Unknown
+
+	case *ast.PropertyType:
+		return &Property{Name: expr.Name.Value, Type: c.typeExpr(expr.Type)}
+
+	case *ast.FunctionType:
+		fn := &Function{Result: c.typeExpr(expr.Result)}
+		for _, param := range expr.Params {
+			fn.Params = append(fn.Params, c.typeExpr(param))
+		}
+		return fn
+
+	case *ast.IntersectionType:
+		t := &Intersection{}
+		for _, member := range expr.Types {
+			t.Types = append(t.Types, c.typeExpr(member))
+		}
+		c.checkIntersection(expr, t)
+		return t
+
+	case *ast.UnionType:
+		t := &Union{}
+		for _, variant := range expr.Variants {
+			vt := c.typeExpr(variant.Type)
+			for _, other := range t.Variants {
+				if sameVariant(vt, other) {
+					c.report(diagnostic.Diagnostic{
+						Severity: diagnostic.Warning,
+						Span:     spanOf(variant),
+						Code:     CodeDuplicateMember,
+						Message:  fmt.Sprintf("duplicate variant %s in union", vt),
+					})
+				}
+			}
+			t.Variants = append(t.Variants, vt)
+		}
+		return t
+	}
+
+	return Unknown
+}
+
+func sameVariant(v, t Type) bool {
+	if v == t {
+		return v != Unknown
+	}
+	vl, ok := v.(*Literal)
+	tl, ok2 := t.(*Literal)
+	return ok && ok2 && vl.Value == tl.Value
+}
+
+// checkIntersection reports intersections which
+// nothing can inhabit, such as int & bool, or
+// 200 & 201
+func (c *Checker) checkIntersection(expr *ast.IntersectionType, t *Intersection) {
+	var base, baseOf Type
+	var lit *Literal
+
+	for _, member := range t.Types {
+		if l, ok := resolve(member).(*Literal); ok {
+			if lit != nil && lit.Value != l.Value {
+				c.errorf(expr, CodeEmptyType, "empty intersection: no value is both %s and %s", lit, l)
+				return
+			}
+			lit = l
+		}
+
+		u := Underlying(member)
+		if _, ok := u.(*Property); ok {
+			u = record
+		}
+		if _, ok := u.(*Basic); !ok || u == Unknown {
+			continue
+		}
+		if base != nil && base != u {
+			c.errorf(expr, CodeEmptyType, "empty intersection: no value is both %s and %s", baseOf, member)
+			return
+		}
+		base, baseOf = u, member
+	}
+}
+
+// record stands in for any type with properties
+// when looking for empty intersections
+var record = &Basic{Name: "record"}
+
+// resolve follows names to what they stand for
+func resolve(t Type) Type {
+	seen := map[*Named]bool{}
+	for {
+		t = prune(t)
+		named, ok := t.(*Named)
+		if !ok || seen[named] || named.Underlying == nil {
+			return t
+		}
+		seen[named] = true
+		t = named.Underlying
+	}
+}
+
+func (c *Checker) statement(stmt ast.Statement) Type {
+	switch stmt := stmt.(type) {
+
+	case *ast.LetStatement:
+		c.let(stmt)
+		return nil
+
+	case *ast.ReturnStatement:
+		t := c.expr(stmt.ReturnValue)
+		if c.fn != nil {
+			c.fn.results = append(c.fn.results, t)
+			c.fn.returns = append(c.fn.returns, stmt)
+		}
+		return nil
+
+	case *ast.ExpressionStatement:
+		return c.expr(stmt.Expression)
+
+	case *ast.BlockStatement:
+		return c.block(stmt)
+	}
+
+	// type declarations were dealt with up front
+	return nil
+}
+
+// block checks the statements of b in their own
+// scope, returning the type of the last one
+func (c *Checker) block(b *ast.BlockStatement) Type {
+	if b == nil {
+		return Unknown
+	}
+
+	outer := c.scope
+	c.scope = NewScope(outer)
+	defer func() { c.scope = outer }()
+
+	c.declareTypes(b.Statements)
+
+	var t Type
+	for _, stmt := range b.Statements {
+		t = c.statement(stmt)
+	}
+	if t == nil {
+		return Unknown
+	}
+	return t
+}
+
+// let infers the type of the bound value, and
+// generalizes it so each use of the name can
+// pick its own types for whatever is left open
+func (c *Checker) let(stmt *ast.LetStatement) {
+	// annotated bindings are exactly what they say
+	if stmt.Type != nil {
+		want := c.typeExpr(stmt.Type)
+		c.scope.DeclareValue(stmt.Name.Value, want)
+
+		t := c.expr(stmt.Value)
+		if !c.assign(t, want, stmt.Value) {
+			c.report(diagnostic.Diagnostic{
+				Severity: diagnostic.Error,
+				Span:     spanOf(stmt.Value),
+				Code:     CodeNotAssignable,
+				Message:  fmt.Sprintf("cannot use %s (of type %s) as %s value in let binding", stmt.Value, t, want),
+				Notes: append([]diagnostic.Note{
+					{Span: spanOf(stmt.Type), Message: fmt.Sprintf("%s is declared as %s here", stmt.Name, want)},
+				}, inferredAt(t)...),
+			})
+		}
+		return
+	}
+
+	c.level++
+
+	// functions may call themselves, closures see
+	// the binding by the time they're invoked
+	var self *TypeVar
+	if _, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		self = c.fresh()
+		c.scope.DeclareValue(stmt.Name.Value, self)
+	}
+
+	// the calls it makes of itself must fit what it is
+	t := c.expr(stmt.Value)
+	if self != nil && !c.unify(self, t, stmt.Value) {
+		c.conflict(stmt.Value, fmt.Sprintf("definition of %s", stmt.Name), stmt.Name, self, stmt.Value, t)
+	}
+
+	c.level--
+	c.scope.DeclareValue(stmt.Name.Value, c.generalize(t))
+}
+
+func (c *Checker) expr(expr ast.Expression) Type {
+	if expr == nil {
+		return Unknown
+	}
+
+	t := c.exprInternal(expr)
+	c.Types[expr] = t
+	return t
+}
+
+func (c *Checker) exprInternal(expr ast.Expression) Type {
+	switch expr := expr.(type) {
+
+	case *ast.IntegerLiteral:
+		return Int
+
+	case *ast.Boolean:
+		return Bool
+
+	case *ast.FloatLiteral:
+		return Float
+
+	case *ast.StringLiteral:
+		return String
+
+	case *ast.Identifier:
+		t, ok := c.scope.LookupValue(expr.Value)
+		if !ok {
+			c.errorf(expr, CodeUndefined, "undefined: %s", expr.Value)
+			return Unknown
+		}
+		if scheme, ok := t.(*Scheme); ok {
+			return instantiate(scheme, c.fresh)
+		}
+		return t
+
+	case *ast.PrefixExpression:
+		return c.prefix(expr)
+
+	case *ast.InfixExpression:
+		return c.infix(expr)
+
+	case *ast.IfExpression:
+		cond := c.expr(expr.Condition)
+		if !c.unify(cond, Bool, expr.Condition) {
+			c.report(diagnostic.Diagnostic{
+				Severity: diagnostic.Error,
+				Span:     spanOf(expr.Condition),
+				Code:     CodeNonBoolean,
+				Message:  fmt.Sprintf("non-boolean condition in if expression: %s (of type %s)", expr.Condition, cond),
+				Notes:    inferredAt(cond),
+			})
+		}
+
+		consequence := c.block(expr.Consequence)
+		if expr.Alternative == nil {
+			return Unknown
+		}
+		alternative := c.block(expr.Alternative)
+		if !c.unify(consequence, alternative, expr) {
+			c.conflict(expr, "if and else branches", expr.Consequence, consequence, expr.Alternative, alternative)
+			return Unknown
+		}
+		return consequence
+
+	case *ast.SwitchExpression:
+		return c.switchExpr(expr)
+
+	case *ast.FunctionLiteral:
+		return c.function(expr)
+
+	case *ast.InvocationExpression:
+		return c.invocation(expr)
+
+	case *ast.RangeExpression:
+		return c.rangeExpr(expr)
+
+	case *ast.ArrayLiteral:
+		return c.array(expr)
+
+	case *ast.IndexExpression:
+		return c.index(expr)
+
+	case *ast.RecordLiteral:
+		return c.record(expr)
+
+	case *ast.HashLiteral:
+		return c.hash(expr)
+
+	case *ast.MemberExpression:
+		return c.member(expr)
+	}
+
+	return Unknown
+}
+
+func (c *Checker) prefix(expr *ast.PrefixExpression) Type {
+	right := c.expr(expr.Right)
+
+	var want Type
+	switch expr.Operator {
+	case "!":
+		want = Bool
+	case "-":
+		want = Int
+		if Underlying(right) == Float {
+			want = Float
+		}
+	default:
+		return Unknown
+	}
+
+	if !c.unify(right, want, expr.Right) {
+		c.invalidOperand(expr, expr.Operator, expr.Right, right)
+	}
+	return want
+}
+
+func (c *Checker) infix(expr *ast.InfixExpression) Type {
+	left := c.expr(expr.Left)
+	right := c.expr(expr.Right)
+
+	lu, ru := Underlying(left), Underlying(right)
+
+	// a float on either side makes
+	// the arithmetic float arithmetic
+	number := Int
+	if lu == Float || ru == Float {
+		number = Float
+	}
+
+	var operand, result Type
+	switch expr.Operator {
+	case "+":
+		// + joins strings too, if either side is one
+		operand, result = number, number
+		if lu == String || ru == String {
+			operand, result = String, String
+		}
+	case "-", "*", "/":
+		operand, result = number, number
+	case "<", ">", "<=", ">=":
+		operand, result = number, Bool
+	case "&&", "||":
+		operand, result = Bool, Bool
+	case "==", "!=":
+		if !(isNumber(lu) && isNumber(ru)) && !c.unify(left, right, expr) {
+			c.mismatched(expr, left, right)
+		}
+		return Bool
+	default:
+		return Unknown
+	}
+
+	switch {
+	case operand == Bool:
+		// either side may be wrong on its own
+		if !c.unify(left, Bool, expr.Left) {
+			c.invalidOperand(expr, expr.Operator, expr.Left, left)
+		}
+		if !c.unify(right, Bool, expr.Right) {
+			c.invalidOperand(expr, expr.Operator, expr.Right, right)
+		}
+	case isKnown(lu) && isKnown(ru) && lu != ru && !(isNumber(lu) && isNumber(ru)):
+		c.mismatched(expr, left, right)
+	case !c.operand(left, operand, expr.Left):
+		c.invalidOperand(expr, expr.Operator, expr.Left, left)
+	case !c.operand(right, operand, expr.Right):
+		c.invalidOperand(expr, expr.Operator, expr.Right, right)
+	}
+	return result
+}
+
+// switchExpr checks the pattern of each arm against the
+// type of the value, and that the results of the arms
+// agree. Arms which can never be reached, being after
+// one that matches anything, or repeating an earlier
+// pattern, are warned about
+func (c *Checker) switchExpr(expr *ast.SwitchExpression) Type {
+	value := c.expr(expr.Value)
+
+	var result Type
+	var first, catchAll ast.Node
+	seen := map[string]bool{}
+
+	for _, arm := range expr.Arms {
+		switch {
+		case catchAll != nil:
+			c.unreachable(arm, fmt.Sprintf("%s above matches everything", catchAll))
+		case seen[arm.Pattern.String()]:
+			c.unreachable(arm, fmt.Sprintf("%s is matched above", arm.Pattern))
+		}
+		seen[arm.Pattern.String()] = true
+
+		outer := c.scope
+		c.scope = NewScope(outer)
+		if c.pattern(arm.Pattern, value) && catchAll == nil {
+			catchAll = arm.Pattern
+		}
+		t := c.expr(arm.Result)
+		c.scope = outer
+
+		if result == nil {
+			result, first = t, arm.Result
+			continue
+		}
+		if !c.unify(result, t, arm.Result) {
+			c.conflict(expr, "switch arms", first, result, arm.Result, t)
+		}
+	}
+
+	if result == nil {
+		return Unknown
+	}
+	return result
+}
+
+func (c *Checker) unreachable(arm *ast.SwitchArm, why string) {
+	c.report(diagnostic.Diagnostic{
+		Severity: diagnostic.Warning,
+		Span:     token.Span{Start: arm.Pos(), End: arm.End()},
+		Code:     CodeUnreachable,
+		Message:  "unreachable switch arm, " + why,
+	})
+}
+
+// pattern checks pattern can match a value of type t,
+// declaring the names it binds in the current scope.
+// It reports whether the pattern matches every value
+// of type t
+func (c *Checker) pattern(pattern ast.Expression, t Type) bool {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value == "_" {
+			return true
+		}
+		pt, ok := c.scope.LookupType(pattern.Value)
+		if !ok {
+			c.scope.DeclareValue(pattern.Value, t)
+			return true
+		}
+		if !isKnown(prune(t)) {
+			return false
+		}
+		if !AssignableTo(pt, t) && !AssignableTo(t, pt) {
+			c.mismatchedPattern(pattern, pt, t)
+			return false
+		}
+		return AssignableTo(t, pt)
+
+	case *ast.RecordPattern:
+		return c.recordPattern(pattern, t)
+
+	default:
+		// literals match the way == compares them,
+		// so numbers match whatever their kind
+		lt := c.expr(pattern)
+		if !(isNumber(Underlying(lt)) && isNumber(Underlying(t))) && !c.unify(lt, t, pattern) {
+			c.mismatchedPattern(pattern, lt, t)
+		}
+		return false
+	}
+}
+
+// recordPattern checks a record pattern against t. For
+// a union, the fields are looked for in its variants
+func (c *Checker) recordPattern(pattern *ast.RecordPattern, t Type) bool {
+	names := []string{}
+	for _, field := range pattern.Fields {
+		names = append(names, field.Name.Value)
+	}
+
+	variants := []Type{t}
+	if u, ok := resolve(t).(*Union); ok {
+		variants = u.Variants
+	}
+
+	// the first variant having all the fields decides
+	// their types, and it can only match everything if
+	// it's all there is
+	for _, variant := range variants {
+		types, ok := fieldTypes(variant, names)
+		if !ok {
+			continue
+		}
+
+		matchesAll := len(variants) == 1
+		for i, field := range pattern.Fields {
+			if !c.pattern(field.Value, types[i]) {
+				matchesAll = false
+			}
+		}
+		return matchesAll
+	}
+
+	if isKnown(prune(t)) {
+		c.report(diagnostic.Diagnostic{
+			Severity: diagnostic.Error,
+			Span:     spanOf(pattern),
+			Code:     CodeMismatchedTypes,
+			Message:  fmt.Sprintf("pattern %s can never match %s value, which lacks its fields", pattern, t),
+			Notes:    inferredAt(t),
+		})
+	}
+	for _, field := range pattern.Fields {
+		c.pattern(field.Value, Unknown)
+	}
+	return false
+}
+
+// fieldTypes gives the types of the named fields of a
+// value of type t, if it has all of them
+func fieldTypes(t Type, names []string) ([]Type, bool) {
+	fields, ok := fieldsOf(t)
+	if !ok {
+		return nil, false
+	}
+
+	types := []Type{}
+	for _, name := range names {
+		found := false
+		for _, field := range fields {
+			if field.Name == name {
+				types = append(types, field.Type)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return types, true
+}
+
+func (c *Checker) mismatchedPattern(pattern ast.Expression, pt, t Type) {
+	c.report(diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Span:     spanOf(pattern),
+		Code:     CodeMismatchedTypes,
+		Message:  fmt.Sprintf("pattern %s (of type %s) can never match %s value", pattern, pt, t),
+		Notes:    inferredAt(pt, t),
+	})
+}
+
+// array works out the type of an array literal,
+// whose elements must all be of the same type
+func (c *Checker) array(expr *ast.ArrayLiteral) Type {
+	var elem Type = c.fresh()
+	var first ast.Node
+
+	for _, element := range expr.Elements {
+		var t Type
+		if spread, ok := element.(*ast.SpreadExpression); !ok {
+			t = c.expr(element)
+		} else {
+			t = c.expr(spread.Value)
+			list := &List{Elem: c.fresh()}
+			if !c.unify(t, list, spread.Value) {
+				c.report(diagnostic.Diagnostic{
+					Severity: diagnostic.Error,
+					Span:     spanOf(spread),
+					Code:     CodeInvalidOperand,
+					Message:  fmt.Sprintf("cannot spread %s (of type %s) into a list", spread.Value, t),
+					Notes:    inferredAt(t),
+				})
+				continue
+			}
+			t = list.Elem
+		}
+
+		if first == nil {
+			first = element
+		}
+		if !c.unify(elem, t, element) {
+			c.conflict(expr, "list literal", first, elem, element, t)
+			return &List{Elem: Unknown}
+		}
+	}
+
+	return &List{Elem: elem}
+}
+
+// hash works out the type of a hash literal, whose
+// keys must all be of one type, and values of another
+func (c *Checker) hash(expr *ast.HashLiteral) Type {
+	t := &Hash{Key: c.fresh(), Value: c.fresh()}
+
+	for i, key := range expr.Keys {
+		kt, vt := c.expr(key), c.expr(expr.Values[i])
+		if !c.unify(t.Key, kt, key) {
+			c.conflict(expr, "hash keys", expr.Keys[0], t.Key, key, kt)
+			return &Hash{Key: Unknown, Value: Unknown}
+		}
+		if !c.unify(t.Value, vt, expr.Values[i]) {
+			c.conflict(expr, "hash values", expr.Values[0], t.Value, expr.Values[i], vt)
+			return &Hash{Key: Unknown, Value: Unknown}
+		}
+	}
+
+	switch key := Underlying(t.Key); {
+	case !isKnown(key), key == Int, key == Bool, key == String:
+	default:
+		c.report(diagnostic.Diagnostic{
+			Severity: diagnostic.Error,
+			Span:     spanOf(expr.Keys[0]),
+			Code:     CodeInvalidOperand,
+			Message:  fmt.Sprintf("invalid hash key type %s, must be int, bool or string", t.Key),
+			Notes:    inferredAt(t.Key),
+		})
+	}
+	return t
+}
+
+// index works out the type of xs[i], which is the
+// element type of the list xs, or of h[k], the value
+// type of the hash h
+func (c *Checker) index(expr *ast.IndexExpression) Type {
+	left := c.expr(expr.Left)
+	index := c.expr(expr.Index)
+
+	if hash, ok := resolve(left).(*Hash); ok {
+		if !c.unify(index, hash.Key, expr.Index) {
+			c.report(diagnostic.Diagnostic{
+				Severity: diagnostic.Error,
+				Span:     spanOf(expr.Index),
+				Code:     CodeMismatchedTypes,
+				Message:  fmt.Sprintf("invalid index %s (of type %s), must be %s", expr.Index, index, hash.Key),
+				Notes:    inferredAt(index, hash.Key),
+			})
+		}
+		return hash.Value
+	}
+
+	list := &List{Elem: c.fresh()}
+	if !c.unify(left, list, expr.Left) {
+		c.report(diagnostic.Diagnostic{
+			Severity: diagnostic.Error,
+			Span:     spanOf(expr),
+			Code:     CodeInvalidOperand,
+			Message:  fmt.Sprintf("invalid operation: cannot index %s (of type %s)", expr.Left, left),
+			Notes:    inferredAt(left),
+		})
+		return Unknown
+	}
+
+	if !c.unify(index, Int, expr.Index) {
+		c.report(diagnostic.Diagnostic{
+			Severity: diagnostic.Error,
+			Span:     spanOf(expr.Index),
+			Code:     CodeMismatchedTypes,
+			Message:  fmt.Sprintf("invalid index %s (of type %s), must be int", expr.Index, index),
+			Notes:    inferredAt(index),
+		})
+	}
+	return list.Elem
+}
+
+// record works out the type of a record literal, which
+// is the intersection of its fields' properties, as in
+// a type declaration. Later fields override earlier ones
+// of the same name, including those spread in
+func (c *Checker) record(expr *ast.RecordLiteral) Type {
+	props := []*Property{}
+	set := func(prop *Property) {
+		for i, p := range props {
+			if p.Name == prop.Name {
+				props[i] = prop
+				return
+			}
+		}
+		props = append(props, prop)
+	}
+
+	known := true
+	written := map[string]bool{}
+	for _, entry := range expr.Entries {
+		switch entry := entry.(type) {
+		case *ast.RecordField:
+			if written[entry.Name.Value] {
+				c.report(diagnostic.Diagnostic{
+					Severity: diagnostic.Warning,
+					Span:     spanOf(entry),
+					Code:     CodeDuplicateMember,
+					Message:  fmt.Sprintf("duplicate field %s in record literal", entry.Name),
+				})
+			}
+			written[entry.Name.Value] = true
+			set(&Property{Name: entry.Name.Value, Type: c.expr(entry.Value)})
+
+		case *ast.SpreadExpression:
+			t := c.expr(entry.Value)
+			fields, ok := fieldsOf(t)
+			switch {
+			case ok:
+				for _, field := range fields {
+					set(field)
+				}
+			case isKnown(t):
+				c.report(diagnostic.Diagnostic{
+					Severity: diagnostic.Error,
+					Span:     spanOf(entry),
+					Code:     CodeInvalidOperand,
+					Message:  fmt.Sprintf("cannot spread %s (of type %s) into a record", entry.Value, t),
+					Notes:    inferredAt(t),
+				})
+				fallthrough
+			default:
+				// we can't tell which fields it brings along
+				known = false
+			}
+		}
+	}
+
+	if !known {
+		return Unknown
+	}
+	if len(props) == 1 {
+		return props[0]
+	}
+	t := &Intersection{}
+	for _, prop := range props {
+		t.Types = append(t.Types, prop)
+	}
+	return t
+}
+
+// member works out the type of r.field from the
+// properties of r's type
+func (c *Checker) member(expr *ast.MemberExpression) Type {
+	t := c.expr(expr.Object)
+
+	fields, ok := fieldsOf(t)
+	if !ok && !isKnown(t) {
+		return Unknown
+	}
+	for _, field := range fields {
+		if field.Name == expr.Property.Value {
+			return field.Type
+		}
+	}
+
+	c.report(diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Span:     spanOf(expr),
+		Code:     CodeUndefined,
+		Message:  fmt.Sprintf("%s undefined (type %s has no field %s)", expr, t, expr.Property),
+		Notes:    inferredAt(t),
+	})
+	return Unknown
+}
+
+// fieldsOf gives back the properties a value of type t
+// has, and whether t is made up of properties at all
+func fieldsOf(t Type) ([]*Property, bool) {
+	switch t := resolve(t).(type) {
+	case *Property:
+		return []*Property{t}, true
+	case *Intersection:
+		fields := []*Property{}
+		for _, member := range t.Types {
+			more, ok := fieldsOf(member)
+			if !ok {
+				return nil, false
+			}
+			fields = append(fields, more...)
+		}
+		return fields, true
+	}
+	return nil, false
+}
+
+// rangeExpr checks the bounds of a range are all
+// ints, a range being a list of them
+func (c *Checker) rangeExpr(expr *ast.RangeExpression) Type {
+	for _, bound := range []ast.Expression{expr.Start, expr.Next, expr.Stop} {
+		if bound == nil {
+			continue
+		}
+		if t := c.expr(bound); !c.unify(t, Int, bound) {
+			c.report(diagnostic.Diagnostic{
+				Severity: diagnostic.Error,
+				Span:     spanOf(bound),
+				Code:     CodeMismatchedTypes,
+				Message:  fmt.Sprintf("range bound %s must be int, not %s", bound, t),
+				Notes:    inferredAt(t),
+			})
+		}
+	}
+	return &List{Elem: Int}
+}
+
+// operand checks a value of type t can be used as an
+// operand of type want, where ints widen to floats
+func (c *Checker) operand(t, want Type, at ast.Node) bool {
+	if want == Float && Underlying(t) == Int {
+		return true
+	}
+	return c.unify(t, want, at)
+}
+
+func isNumber(t Type) bool {
+	return t == Int || t == Float
+}
+
+// isKnown reports whether t has been worked out
+func isKnown(t Type) bool {
+	if _, ok := t.(*TypeVar); ok {
+		return false
+	}
+	return t != Unknown
+}
+
+func (c *Checker) invalidOperand(expr ast.Expression, operator string, operand ast.Expression, t Type) {
+	c.report(diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Span:     spanOf(expr),
+		Code:     CodeInvalidOperand,
+		Message:  fmt.Sprintf("invalid operation: operator %s not defined on %s (of type %s)", operator, operand, t),
+		Notes:    inferredAt(t),
+	})
+}
+
+func (c *Checker) mismatched(expr *ast.InfixExpression, left, right Type) {
+	c.report(diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Span:     spanOf(expr),
+		Code:     CodeMismatchedTypes,
+		Message:  fmt.Sprintf("invalid operation: %s (mismatched types %s and %s)", expr, left, right),
+		Notes: append([]diagnostic.Note{
+			{Span: spanOf(expr.Left), Message: fmt.Sprintf("this is of type %s", left)},
+			{Span: spanOf(expr.Right), Message: fmt.Sprintf("this is of type %s", right)},
+		}, inferredAt(left, right)...),
+	})
+}
+
+// conflict reports two parts of expr which should
+// have had the same type, but didn't
+func (c *Checker) conflict(expr ast.Node, what string, a ast.Node, at Type, b ast.Node, bt Type) {
+	c.report(diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Span:     spanOf(expr),
+		Code:     CodeMismatchedTypes,
+		Message:  fmt.Sprintf("mismatched types %s and %s in %s", at, bt, what),
+		Notes: append([]diagnostic.Note{
+			{Span: spanOf(a), Message: fmt.Sprintf("this is of type %s", at)},
+			{Span: spanOf(b), Message: fmt.Sprintf("this is of type %s", bt)},
+		}, inferredAt(at, bt)...),
+	})
+}
+
+// inferredAt points at wherever the types
+// we inferred were decided on
+func inferredAt(types ...Type) []diagnostic.Note {
+	notes := []diagnostic.Note{}
+	for _, t := range types {
+		if node := origin(t); node != nil {
+			notes = append(notes, diagnostic.Note{
+				Span:    spanOf(node),
+				Message: fmt.Sprintf("%s was inferred here", prune(t)),
+			})
+		}
+	}
+	return notes
+}
+
+func (c *Checker) function(expr *ast.FunctionLiteral) Type {
+	outer, outerFn := c.scope, c.fn
+	c.scope = NewScope(outer)
+	c.fn = &function{}
+	defer func() { c.scope, c.fn = outer, outerFn }()
+
+	fn := &Function{}
+	for i, arg := range expr.Arguments {
+		var param Type = c.fresh()
+		if i < len(expr.ArgumentTypes) && expr.ArgumentTypes[i] != nil {
+			param = c.typeExpr(expr.ArgumentTypes[i])
+		}
+		fn.Params = append(fn.Params, param)
+		c.scope.DeclareValue(arg.Value, param)
+	}
+
+	body := c.block(expr.Body)
+	results := append(c.fn.results, body)
+	sites := append(c.fn.returns, ast.Node(expr.Body))
+
+	// an annotated result is what everything
+	// else has to live up to
+	if expr.ReturnType != nil {
+		want := c.typeExpr(expr.ReturnType)
+		for i, result := range results {
+			if results[i] == Unknown && sites[i] == ast.Node(expr.Body) {
+				continue
+			}
+			if !c.assign(result, want, returned(sites[i])) {
+				c.report(diagnostic.Diagnostic{
+					Severity: diagnostic.Error,
+					Span:     spanOf(returned(sites[i])),
+					Code:     CodeNotAssignable,
+					Message:  fmt.Sprintf("cannot use %s as %s value in return", result, want),
+					Notes: append([]diagnostic.Note{
+						{Span: spanOf(expr.ReturnType), Message: fmt.Sprintf("the result is declared as %s here", want)},
+					}, inferredAt(result)...),
+				})
+			}
+		}
+		fn.Result = want
+		return fn
+	}
+
+	// every return, and the value the body ends
+	// with, must agree on what the function gives
+	fn.Result = results[0]
+	for i := 1; i < len(results); i++ {
+		// blocks ending in a statement have no value
+		if results[i] == Unknown && sites[i] == ast.Node(expr.Body) {
+			continue
+		}
+		if !c.unify(fn.Result, results[i], sites[i]) {
+			c.conflict(expr, "function results", sites[0], fn.Result, sites[i], results[i])
+		}
+	}
+	return fn
+}
+
+func (c *Checker) invocation(expr *ast.InvocationExpression) Type {
+	callee := prune(c.expr(expr.Function))
+
+	args := []Type{}
+	for _, arg := range expr.Arguments {
+		args = append(args, c.expr(arg))
+	}
+
+	if callee == Unknown {
+		return Unknown
+	}
+
+	// calling something we know nothing about
+	// tells us it's a function taking these args
+	if tv, ok := callee.(*TypeVar); ok {
+		fn := &Function{Params: args, Result: c.fresh()}
+		if !c.unify(tv, fn, expr.Function) {
+			c.conflict(expr, fmt.Sprintf("call to %s", expr.Function), expr.Function, tv, expr, fn)
+		}
+		return fn.Result
+	}
+
+	fn, ok := resolve(callee).(*Function)
+	if !ok {
+		c.errorf(expr, CodeNotCallable, "invalid operation: cannot call non-function %s (of type %s)", expr.Function, callee)
+		return Unknown
+	}
+
+	if len(args) != len(fn.Params) {
+		problem := "not enough"
+		if len(args) > len(fn.Params) {
+			problem = "too many"
+		}
+		c.errorf(expr, CodeArgumentCount, "%s arguments in call to %s, have %d, want %d", problem, expr.Function, len(args), len(fn.Params))
+		return fn.Result
+	}
+
+	for i, arg := range expr.Arguments {
+		if !c.assign(args[i], fn.Params[i], arg) {
+			c.report(diagnostic.Diagnostic{
+				Severity: diagnostic.Error,
+				Span:     spanOf(arg),
+				Code:     CodeNotAssignable,
+				Message:  fmt.Sprintf("cannot use %s (of type %s) as %s value in argument to %s", arg, args[i], fn.Params[i], expr.Function),
+				Notes:    inferredAt(args[i], fn.Params[i]),
+			})
+		}
+	}
+
+	return fn.Result
+}
+
+// constant narrows the type t of expr to a literal
+// type when expr is a literal, so 200 can be used
+// where a type Ok = 200 is wanted
+func (c *Checker) constant(expr ast.Node, t Type) Type {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		return &Literal{Value: expr.Value, Base: Int}
+	case *ast.Boolean:
+		return &Literal{Value: expr.Value, Base: Bool}
+	}
+	return t
+}
+
+// returned finds the expression a function result
+// came from, given the return statement or body
+func returned(site ast.Node) ast.Node {
+	switch site := site.(type) {
+	case *ast.ReturnStatement:
+		if site.ReturnValue != nil {
+			return site.ReturnValue
+		}
+	case *ast.BlockStatement:
+		if len(site.Statements) > 0 {
+			if stmt, ok := site.Statements[len(site.Statements)-1].(*ast.ExpressionStatement); ok {
+				return stmt.Expression
+			}
+		}
+	}
+	return site
+}
+
+// assign checks a value of type v, found at the node
+// at, may be used as a t. For types still being inferred
+// that means unifying them, for known ones it means v
+// is assignable
+func (c *Checker) assign(v, t Type, at ast.Node) bool {
+	// types still being worked out, such as []'a, are
+	// left to unification to fill in
+	inferring := len(freeVars(v, nil)) > 0 || len(freeVars(t, nil)) > 0
+	if !inferring {
+		_, vf := prune(v).(*Function)
+		_, tf := prune(t).(*Function)
+		if !vf || !tf {
+			return AssignableTo(c.constant(at, v), t)
+		}
+	}
+	return c.unify(v, t, at)
+}
+
+func (c *Checker) report(d diagnostic.Diagnostic) {
+	c.errors = append(c.errors, d)
+}
+
+func (c *Checker) errorf(node ast.Node, code, format string, args ...interface{}) {
+	c.report(diagnostic.Diagnostic{
+		Severity: diagnostic.Error,
+		Span:     spanOf(node),
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func spanOf(node ast.Node) token.Span {
+	return token.Span{Start: node.Pos(), End: node.End()}
+}
@@ -0,0 +1,717 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/SCKelemen/oak/ast"
+	"github.com/SCKelemen/oak/diagnostic"
+	"github.com/SCKelemen/oak/parser"
+	"github.com/SCKelemen/oak/scanner"
+)
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"1 + 2 * 3", []string{}},
+		{"true + 1", []string{"1:1: invalid operation: (true + 1) (mismatched types bool and int)"}},
+		{"true + false", []string{"1:1: invalid operation: operator + not defined on true (of type bool)"}},
+		{"5 < true", []string{"1:1: invalid operation: (5 < true) (mismatched types int and bool)"}},
+		{"1 == false", []string{"1:1: invalid operation: (1 == false) (mismatched types int and bool)"}},
+		{"!5", []string{"1:1: invalid operation: operator ! not defined on 5 (of type int)"}},
+		{"-true", []string{"1:1: invalid operation: operator - not defined on true (of type bool)"}},
+		{"foo", []string{"1:1: undefined: foo"}},
+		{"if (1) { 2 }", []string{"1:5: non-boolean condition in if expression: 1 (of type int)"}},
+		{"let x = 5; x(1)", []string{"1:12: invalid operation: cannot call non-function x (of type int)"}},
+		{"let f = func(a, b) { a }; f(1)", []string{"1:27: not enough arguments in call to f, have 1, want 2"}},
+		{"let f = func(a) { a }; f(1, 2)", []string{"1:24: too many arguments in call to f, have 2, want 1"}},
+		{"let f = func(a) { 1 }; f(1) + 1", []string{}},
+		{"let f = func(a) { true }; f(1) + 1", []string{"1:27: invalid operation: (f(1) + 1) (mismatched types bool and int)"}},
+		{"let f = func(a) { a + 1 }; f(true)", []string{"1:30: cannot use true (of type bool) as int value in argument to f"}},
+		{"let id = func(x) { x }; id(1) + 1; !id(true)", []string{}},
+		{"let f = func(x) { if (x) { 1 } else { 2 } }; f(1)", []string{"1:48: cannot use 1 (of type int) as bool value in argument to f"}},
+		{"let f = func(x) { if (x) { 1 } else { true } }", []string{"1:19: mismatched types int and bool in if and else branches"}},
+		{"let f = func(x) { if (x) { return 1; } true }", []string{"1:9: mismatched types int and bool in function results"}},
+		{"let f = func(x) { x(x) }", []string{"1:19: mismatched types 'b and func('b): 'c in call to x"}},
+		{"let apply = func(f, x) { f(x) }; apply(func(n) { n + 1 }, true)", []string{"1:59: cannot use true (of type bool) as int value in argument to apply"}},
+	}
+
+	for _, tt := range tests {
+		diags := check(t, tt.input)
+		testDiagnostics(t, tt.input, diags, tt.expected)
+	}
+}
+
+func TestCheckTypeDeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			`Lexer: type
+			  = input:    string
+			  & current:  char
+			  & position: int
+			  & readPos:  int`,
+			[]string{},
+		},
+		{
+			`type StatusCode = | SuccessCode | NotFound
+			 type SuccessCode = | Ok | Created
+			 type Ok = 200
+			 type Created = 201
+			 type NotFound = 404`,
+			[]string{},
+		},
+		{"type A = B", []string{"1:10: undefined type: B"}},
+		{"type A = int\ntype A = bool", []string{"2:6: type A redeclared"}},
+		{"type A = A", []string{"1:6: invalid recursive type A"}},
+		{"type A = | B | int\ntype B = A", []string{"1:6: invalid recursive type A", "2:6: invalid recursive type B"}},
+		{"List: type = head: int & tail: List", []string{}},
+		{"type Never = int & bool", []string{"1:14: empty intersection: no value is both int and bool"}},
+		{"type Never = 200 & 201", []string{"1:14: empty intersection: no value is both 200 and 201"}},
+		{"type Never = value: int & int", []string{"1:14: empty intersection: no value is both value: int and int"}},
+		{"type Twice = | 1 | 2 | 1", []string{"1:22: warning: duplicate variant 1 in union"}},
+	}
+
+	for _, tt := range tests {
+		diags := check(t, tt.input)
+		testDiagnostics(t, tt.input, diags, tt.expected)
+	}
+}
+
+func TestAssignableTo(t *testing.T) {
+	ok := &Literal{Value: int64(200), Base: Int}
+	created := &Literal{Value: int64(201), Base: Int}
+	success := &Named{Name: "SuccessCode", Underlying: &Union{Variants: []Type{ok, created}}}
+
+	tests := []struct {
+		v, t   Type
+		expecc bool
+	}{
+		{Int, Int, true},
+		{Int, Bool, false},
+		{ok, Int, true},
+		{Int, ok, false},
+		{ok, success, true},
+		{&Literal{Value: int64(404), Base: Int}, success, false},
+		{success, Int, true},
+		{success, ok, false},
+		{Unknown, Bool, true},
+		{Bool, Unknown, true},
+		{&Function{Params: []Type{Int}, Result: Bool}, &Function{Params: []Type{Int}, Result: Bool}, true},
+		{&Function{Params: []Type{Int}, Result: Bool}, &Function{Params: []Type{Bool}, Result: Bool}, false},
+	}
+
+	for _, tt := range tests {
+		if AssignableTo(tt.v, tt.t) != tt.expecc {
+			t.Errorf("AssignableTo(%s, %s) expecc %t", tt.v, tt.t, tt.expecc)
+		}
+	}
+}
+
+func TestCheckRecordsTypes(t *testing.T) {
+	program := parse(t, "let f = func(a, b) { a < b }; f(1, 2)")
+
+	checker := New()
+	if diags := checker.Check(program); len(diags) != 0 {
+		t.Fatalf("checker had errors: %v", diags)
+	}
+
+	stmt := program.Statements[1].(*ast.ExpressionStatement)
+	if typ := checker.Types[stmt.Expression]; typ != Bool {
+		t.Errorf("f(1, 2) has type %v, expected bool", typ)
+	}
+
+	f, ok := checker.Scope().LookupValue("f")
+	if !ok {
+		t.Fatalf("f was not declared")
+	}
+	if f.String() != "func(int, int): bool" {
+		t.Errorf("f has type %s, expected func(int, int): bool", f)
+	}
+}
+
+// TestRollback checks one line at a time, as the REPL does
+func TestRollback(t *testing.T) {
+	checker := New()
+	lines := []struct {
+		input    string
+		expected []string
+		rollback bool
+	}{
+		{"let x = 1 + true", []string{"1:9: invalid operation: (1 + true) (mismatched types int and bool)"}, true},
+		{"x + 1", []string{"1:1: undefined: x"}, true},
+		{"type T = int", nil, false},
+		{"type T = bool", []string{"1:6: type T redeclared"}, true},
+		{"let y: T = 1", nil, false},
+	}
+
+	for _, tt := range lines {
+		diags := checker.Check(parse(t, tt.input))
+		testDiagnostics(t, tt.input, diags, tt.expected)
+		if tt.rollback {
+			checker.Rollback()
+		}
+	}
+
+	// the redeclaration can't point into an earlier line
+	diags := checker.Check(parse(t, "type T = bool"))
+	if len(diags) != 1 || len(diags[0].Notes) != 1 || diags[0].Notes[0].Span.IsValid() {
+		t.Fatalf("expected one note without a span, received %v", diags)
+	}
+	if note := diags[0].Notes[0].Message; note != "previously declared by an earlier input" {
+		t.Errorf("note was %q", note)
+	}
+
+	// but can within the same one
+	diags = New().Check(parse(t, "type U = int\ntype U = bool"))
+	if len(diags) != 1 || len(diags[0].Notes) != 1 || diags[0].Notes[0].Span.Start.Line != 1 {
+		t.Errorf("expected a note pointing at line 1, received %v", diags)
+	}
+}
+
+func TestInference(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		expected string
+	}{
+		{"let x = 5", "x", "int"},
+		{"let id = func(x) { x }", "id", "func('a): 'a"},
+		{"let const = func(a, b) { a }", "const", "func('a, 'b): 'a"},
+		{"let add = func(a, b) { a + b }", "add", "func(int, int): int"},
+		{"let not = func(a) { !a }", "not", "func(bool): bool"},
+		{"let apply = func(f, x) { f(x) }", "apply", "func(func('a): 'b, 'a): 'b"},
+		{"let compose = func(f, g) { func(x) { f(g(x)) } }", "compose", "func(func('a): 'b, func('c): 'a): func('c): 'b"},
+		{"let id = func(x) { x }; let n = id(5)", "n", "int"},
+		{"let id = func(x) { x }; let b = id(true)", "b", "bool"},
+		{"let fact = func(n) { if (n < 2) { return 1; } n * fact(n - 1) }", "fact", "func(int): int"},
+		{"let choose = func(c, a, b) { if (c) { a } else { b } }", "choose", "func(bool, 'a, 'a): 'a"},
+	}
+
+	for _, tt := range tests {
+		checker := New()
+		if diags := checker.Check(parse(t, tt.input)); len(diags) != 0 {
+			t.Errorf("%q: checker had errors: %v", tt.input, diags)
+			continue
+		}
+
+		typ, ok := checker.Scope().LookupValue(tt.name)
+		if !ok {
+			t.Errorf("%q: %s was not declared", tt.input, tt.name)
+			continue
+		}
+		if typ.String() != tt.expected {
+			t.Errorf("%q: %s has type %s, expected %s", tt.input, tt.name, typ, tt.expected)
+		}
+	}
+}
+
+// TestInferenceErrors checks unification failures point
+// at both of the places which disagree
+func TestInferenceErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let g = func(h) { h(h) }; 1", []string{"1:19: mismatched types 'b and func('b): 'c in call to h"}},
+		{"let f = func(x) { f(1, 2) }; 1", []string{"1:9: mismatched types func(int, int): 'c and func('b): 'c in definition of f"}},
+		{"let f = func(x) { f }; 1", []string{"1:9: mismatched types 'a and func('b): 'a in definition of f"}},
+	}
+
+	for _, tt := range tests {
+		diags := check(t, tt.input)
+		testDiagnostics(t, tt.input, diags, tt.expected)
+
+		// the two sites, and maybe where they were inferred
+		if len(diags) == 1 && len(diags[0].Notes) < 2 {
+			t.Errorf("%q: expected notes on both sites, received %v", tt.input, diags[0].Notes)
+		}
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let n: int = 5", []string{}},
+		{"let n: bool = 5", []string{"1:15: cannot use 5 (of type int) as bool value in let binding"}},
+		{"type Ok = 200\nlet code: Ok = 200", []string{}},
+		{"type Ok = 200\nlet code: Ok = 201", []string{"2:16: cannot use 201 (of type int) as Ok value in let binding"}},
+		{"type Code = | 200 | 404\nlet code: Code = 404; code + 1", []string{}},
+		{"let add = func(x: int, y: int): int { x + y }; add(1, true)", []string{"1:55: cannot use true (of type bool) as int value in argument to add"}},
+		{"let f = func(x: bool) { x + 1 }", []string{"1:25: invalid operation: (x + 1) (mismatched types bool and int)"}},
+		{"let f = func(x): bool { x + 1 }", []string{"1:25: cannot use int as bool value in return"}},
+		{"let f = func(x): int { if (x) { return true; } 1 }", []string{"1:40: cannot use bool as int value in return"}},
+		{"let f: func(int): int = func(x) { x }", []string{}},
+		{"let f: func(int): int = func(x) { !x }", []string{"1:25: cannot use func(x)(!x) (of type func(bool): bool) as func(int): int value in let binding"}},
+		{"let f = func(x: Missing) { x }", []string{"1:17: undefined type: Missing"}},
+	}
+
+	for _, tt := range tests {
+		diags := check(t, tt.input)
+		testDiagnostics(t, tt.input, diags, tt.expected)
+	}
+}
+
+func TestAnnotatedInference(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		expected string
+	}{
+		{"let n: int = 5", "n", "int"},
+		{"let f = func(x: int, y) { y }", "f", "func(int, 'a): 'a"},
+		{"let f = func(x): bool { x }", "f", "func(bool): bool"},
+		{"type Ok = 200\nlet f = func(x: Ok) { x }", "f", "func(Ok): Ok"},
+	}
+
+	for _, tt := range tests {
+		checker := New()
+		if diags := checker.Check(parse(t, tt.input)); len(diags) != 0 {
+			t.Errorf("%q: checker had errors: %v", tt.input, diags)
+			continue
+		}
+
+		typ, _ := checker.Scope().LookupValue(tt.name)
+		if typ == nil || typ.String() != tt.expected {
+			t.Errorf("%q: %s has type %v, expected %s", tt.input, tt.name, typ, tt.expected)
+		}
+	}
+}
+
+func TestUnificationNotes(t *testing.T) {
+	input := "let f = func(x) {\n  if (x) { 1 } else { 2 }\n  x + 1\n}"
+
+	diags := check(t, input)
+	if len(diags) != 1 {
+		t.Fatalf("expected 1 diagnostic, received %d: %v", len(diags), diags)
+	}
+
+	d := diags[0]
+	if d.Code != CodeMismatchedTypes {
+		t.Errorf("expected code %s, received %s", CodeMismatchedTypes, d.Code)
+	}
+
+	// the note explaining why x is a bool should
+	// point back at the condition of the if
+	found := false
+	for _, note := range d.Notes {
+		pos := note.Span.Start
+		found = found || pos.Line == 2 && pos.Column == 7 && note.Message == "bool was inferred here"
+	}
+	if !found {
+		t.Errorf("no note points at the if condition at 2:7, received %v", d.Notes)
+	}
+	if pos := d.Span.Start; pos.Line != 3 || pos.Column != 3 {
+		t.Errorf("diagnostic points at %s, expected x + 1 at 3:3", pos)
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	lxr := scanner.New(input)
+	p := parser.New(lxr)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser had errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func check(t *testing.T, input string) []diagnostic.Diagnostic {
+	return New().Check(parse(t, input))
+}
+
+func testDiagnostics(t *testing.T, input string, diags []diagnostic.Diagnostic, expected []string) {
+	if len(diags) != len(expected) {
+		t.Errorf("%q: expected %d diagnostics, received %d: %v", input, len(expected), len(diags), diags)
+		return
+	}
+	for i, msg := range expected {
+		if diags[i].String() != msg {
+			t.Errorf("%q: expected %q, received %q", input, msg, diags[i])
+		}
+	}
+}
+
+func TestBuiltinTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		expected string
+	}{
+		{"let m = map", "m", "func(func('a): 'b, []'a): []'b"},
+		{"let r = reduce", "r", "func(func('a, 'b): 'a, 'a, []'b): 'a"},
+		{"let incAll = func(xs) { map(func(x) { x + 1 }, xs) }", "incAll", "func([]int): []int"},
+		{"let evens = func(xs) { filter(func(x) { x / 2 * 2 == x }, xs) }", "evens", "func([]int): []int"},
+		{"let sum = func(xs) { reduce(func(a, b) { a + b }, 0, xs) }", "sum", "func([]int): int"},
+		{"let first = func(xs) { take(1, xs) }", "first", "func([]'a): []'a"},
+		{"let indexed = func(xs) { transform(func(x, i) { i }, xs) }", "indexed", "func([]'a): []int"},
+		{"let map = func(x) { x }; let n = map(1)", "n", "int"},
+	}
+
+	for _, tt := range tests {
+		checker := New()
+		if diags := checker.Check(parse(t, tt.input)); len(diags) != 0 {
+			t.Errorf("%q: checker had errors: %v", tt.input, diags)
+			continue
+		}
+
+		typ, _ := checker.Scope().LookupValue(tt.name)
+		if typ == nil || typ.String() != tt.expected {
+			t.Errorf("%q: %s has type %v, expected %s", tt.input, tt.name, typ, tt.expected)
+		}
+	}
+
+	diags := check(t, "let f = func(xs) { filter(func(x) { x + 1 }, xs) }")
+	if len(diags) != 1 || diags[0].Code != CodeNotAssignable {
+		t.Errorf("filter with a non-boolean predicate, expected one assignability error, received %v", diags)
+	}
+}
+
+func TestStringTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let s = "oak" + "tree"`, nil},
+		{`let greet = func(name) { "hi, " + name }; let g = greet("oak")`, nil},
+		{`let n = len("oak") + 1`, nil},
+		{`type Name = string; let n: Name = "oak"`, nil},
+		{`"oak" + 1`, []string{`1:1: invalid operation: ("oak" + 1) (mismatched types string and int)`}},
+		{`"oak" - "tree"`, []string{`1:1: invalid operation: operator - not defined on "oak" (of type string)`}},
+		{`"oak" == true`, []string{`1:1: invalid operation: ("oak" == true) (mismatched types string and bool)`}},
+		{`let n: int = "oak"`, []string{`1:14: cannot use "oak" (of type string) as int value in let binding`}},
+	}
+
+	for _, tt := range tests {
+		testDiagnostics(t, tt.input, check(t, tt.input), tt.expected)
+	}
+
+	checker := New()
+	checker.Check(parse(t, `let greet = func(name) { "hi, " + name }`))
+	if typ, _ := checker.Scope().LookupValue("greet"); typ == nil || typ.String() != "func(string): string" {
+		t.Errorf("greet has type %v, expected func(string): string", typ)
+	}
+}
+
+func TestFloatTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1.5 * 2", nil},
+		{"let x: float = 1 + 0.5", nil},
+		{"let x = -1.5 < 2", nil},
+		{"let b = 2 == 2.0", nil},
+		{"let x: int = 1.5", []string{"1:14: cannot use 1.5 (of type float) as int value in let binding"}},
+		{"let x: int = 1 + 0.5", []string{"1:14: cannot use (1 + 0.5) (of type float) as int value in let binding"}},
+		{"1.5 + true", []string{"1:1: invalid operation: (1.5 + true) (mismatched types float and bool)"}},
+	}
+
+	for _, tt := range tests {
+		testDiagnostics(t, tt.input, check(t, tt.input), tt.expected)
+	}
+
+	checker := New()
+	checker.Check(parse(t, "let half = func(x) { x * 0.5 }"))
+	if typ, _ := checker.Scope().LookupValue("half"); typ == nil || typ.String() != "func(float): float" {
+		t.Errorf("half has type %v, expected func(float): float", typ)
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let ok = 1 <= 2 && 2.5 >= 2 || false", nil},
+		{"let both = func(a, b) { a && b }", nil},
+		{"1 && true", []string{"1:1: invalid operation: operator && not defined on 1 (of type int)"}},
+		{"1 || 2", []string{
+			"1:1: invalid operation: operator || not defined on 1 (of type int)",
+			"1:1: invalid operation: operator || not defined on 2 (of type int)",
+		}},
+		{"true <= false", []string{"1:1: invalid operation: operator <= not defined on true (of type bool)"}},
+	}
+
+	for _, tt := range tests {
+		testDiagnostics(t, tt.input, check(t, tt.input), tt.expected)
+	}
+
+	checker := New()
+	checker.Check(parse(t, "let both = func(a, b) { a && b }"))
+	if typ, _ := checker.Scope().LookupValue("both"); typ == nil || typ.String() != "func(bool, bool): bool" {
+		t.Errorf("both has type %v, expected func(bool, bool): bool", typ)
+	}
+}
+
+func TestPipeTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		expected string
+	}{
+		{"let evens = func(xs) { xs |> filter(func(x) { x / 2 * 2 == x }) }", "evens", "func([]int): []int"},
+		{"let total = func(xs) { xs |> map(func(x) { x * 2 }) |> reduce(func(a, b) { a + b }, 0) }", "total", "func([]int): int"},
+		{"let inc = func(x) { x + 1 }; let n = inc <| inc <| 1", "n", "int"},
+	}
+
+	for _, tt := range tests {
+		checker := New()
+		if diags := checker.Check(parse(t, tt.input)); len(diags) != 0 {
+			t.Errorf("%q: checker had errors: %v", tt.input, diags)
+			continue
+		}
+
+		typ, _ := checker.Scope().LookupValue(tt.name)
+		if typ == nil || typ.String() != tt.expected {
+			t.Errorf("%q: %s has type %v, expected %s", tt.input, tt.name, typ, tt.expected)
+		}
+	}
+
+	testDiagnostics(t, "let inc = func(x) { x + 1 }; true |> inc", check(t, "let inc = func(x) { x + 1 }; true |> inc"), []string{
+		"1:30: cannot use true (of type bool) as int value in argument to inc",
+	})
+}
+
+func TestRangeTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		expected string
+	}{
+		{"let r = [1..10]", "r", "[]int"},
+		{"let r = func(n) { [0, 2..<n] }", "r", "func(int): []int"},
+		{"let squares = [1..10] |> map(func(x) { x * x })", "squares", "[]int"},
+	}
+
+	for _, tt := range tests {
+		checker := New()
+		if diags := checker.Check(parse(t, tt.input)); len(diags) != 0 {
+			t.Errorf("%q: checker had errors: %v", tt.input, diags)
+			continue
+		}
+
+		typ, _ := checker.Scope().LookupValue(tt.name)
+		if typ == nil || typ.String() != tt.expected {
+			t.Errorf("%q: %s has type %v, expected %s", tt.input, tt.name, typ, tt.expected)
+		}
+	}
+
+	testDiagnostics(t, `[1.."ten"]`, check(t, `[1.."ten"]`), []string{
+		`1:5: range bound "ten" must be int, not string`,
+	})
+}
+
+func TestRecordTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		expected string
+	}{
+		{"let r = {a: 1}", "r", "a: int"},
+		{"let r = {a: 1, b: true}", "r", "a: int & b: bool"},
+		{"let r = {}", "r", "{}"},
+		{"let r = {a: 1, b: true}; let s = {...r, b: 1, c: 2.5}", "s", "a: int & b: int & c: float"},
+		{"let r = {a: {b: 1}}; let n = r.a.b", "n", "int"},
+		{"let mk = func(x) { {value: x} }; let n = mk(1).value", "n", "int"},
+		{"type Point = x: int & y: int; let p = func(q: Point) { q.x }; let n = p({x: 1, y: 2})", "n", "int"},
+		{"let f = func(x) { {value: x} }", "f", "func('a): value: 'a"},
+	}
+
+	for _, tt := range tests {
+		checker := New()
+		if diags := checker.Check(parse(t, tt.input)); len(diags) != 0 {
+			t.Errorf("%q: checker had errors: %v", tt.input, diags)
+			continue
+		}
+
+		typ, _ := checker.Scope().LookupValue(tt.name)
+		if typ == nil || typ.String() != tt.expected {
+			t.Errorf("%q: %s has type %v, expected %s", tt.input, tt.name, typ, tt.expected)
+		}
+	}
+
+	diagnostics := []struct {
+		input    string
+		expected []string
+	}{
+		{"let r = {a: 1}; r.b", []string{"1:17: r.b undefined (type a: int has no field b)"}},
+		{"let n = 1; n.b", []string{"1:12: n.b undefined (type int has no field b)"}},
+		{"{...1}", []string{"1:2: cannot spread 1 (of type int) into a record"}},
+		{"{a: 1, a: 2}", []string{"1:8: warning: duplicate field a in record literal"}},
+		{"type Point = x: int & y: int; let p = func(q: Point) { q }; p({x: 1})", []string{
+			"1:63: cannot use {x: 1} (of type x: int) as Point value in argument to p",
+		}},
+		{"let r = {a: 1}; r.a + true", []string{"1:17: invalid operation: (r.a + true) (mismatched types int and bool)"}},
+	}
+
+	for _, tt := range diagnostics {
+		testDiagnostics(t, tt.input, check(t, tt.input), tt.expected)
+	}
+}
+
+func TestArrayTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		expected string
+	}{
+		{"let xs = [1, 2, 3]", "xs", "[]int"},
+		{"let xs = [[true], []]", "xs", "[][]bool"},
+		{"let xs = [1, 2]; let ys = [0, ...xs]", "ys", "[]int"},
+		{"let xs = [1, 2]; let n = xs[0]", "n", "int"},
+		{"let f = func(xs, i) { xs[i] }", "f", "func([]'a, int): 'a"},
+		{"let n = first([1..3])", "n", "int"},
+		{"let xs = rest([true])", "xs", "[]bool"},
+		{"let xs = [1] |> push(2)", "xs", "[]int"},
+		{"let n = len([1]) + len(\"abc\")", "n", "int"},
+	}
+
+	for _, tt := range tests {
+		checker := New()
+		if diags := checker.Check(parse(t, tt.input)); len(diags) != 0 {
+			t.Errorf("%q: checker had errors: %v", tt.input, diags)
+			continue
+		}
+
+		typ, _ := checker.Scope().LookupValue(tt.name)
+		if typ == nil || typ.String() != tt.expected {
+			t.Errorf("%q: %s has type %v, expected %s", tt.input, tt.name, typ, tt.expected)
+		}
+	}
+
+	diagnostics := []struct {
+		input    string
+		expected []string
+	}{
+		{"[1, true]", []string{"1:1: mismatched types int and bool in list literal"}},
+		{"let n = 1; n[0]", []string{"1:12: invalid operation: cannot index n (of type int)"}},
+		{"[1][true]", []string{"1:5: invalid index true (of type bool), must be int"}},
+		{"[...1]", []string{"1:2: cannot spread 1 (of type int) into a list"}},
+		{"push(true, [1])", []string{"1:12: cannot use [1] (of type []int) as []bool value in argument to push"}},
+	}
+
+	for _, tt := range diagnostics {
+		testDiagnostics(t, tt.input, check(t, tt.input), tt.expected)
+	}
+}
+
+func TestHashTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		expected string
+	}{
+		{`let h = {200: "OK", 404: "Not Found"}`, "h", "map[int]string"},
+		{`let h = {"a": [1], "b": []}`, "h", "map[string][]int"},
+		{`let h = {200: "OK"}; let s = h[200]`, "s", "string"},
+		{`let h = {true: 1.5}; let ks = keys(h)`, "ks", "[]bool"},
+		{`let h = {true: 1.5}; let vs = values(h)`, "vs", "[]float"},
+		{`let h = {1: 2}; let b = has(1, h)`, "b", "bool"},
+		{`let h = {:}`, "h", "map['a]'b"},
+		{`let ks = keys({:})`, "ks", "[]'a"},
+		{`let b = has("a", {:})`, "b", "bool"},
+		{`let lookup = func(h, k) { if (has(k, h)) { h[k] } else { 0 } }; let n = lookup({"a": 1}, "a")`, "n", "int"},
+	}
+
+	for _, tt := range tests {
+		checker := New()
+		if diags := checker.Check(parse(t, tt.input)); len(diags) != 0 {
+			t.Errorf("%q: checker had errors: %v", tt.input, diags)
+			continue
+		}
+
+		typ, _ := checker.Scope().LookupValue(tt.name)
+		if typ == nil || typ.String() != tt.expected {
+			t.Errorf("%q: %s has type %v, expected %s", tt.input, tt.name, typ, tt.expected)
+		}
+	}
+
+	diagnostics := []struct {
+		input    string
+		expected []string
+	}{
+		{`{1: "a", "b": "c"}`, []string{"1:1: mismatched types int and string in hash keys"}},
+		{`{1: "a", 2: 3}`, []string{"1:1: mismatched types string and int in hash values"}},
+		{`{1.5: "a"}`, []string{"1:2: invalid hash key type float, must be int, bool or string"}},
+		{`{1: "a"}["b"]`, []string{`1:10: invalid index "b" (of type string), must be int`}},
+		{`has("a", {1: 2})`, []string{"1:10: cannot use {1: 2} (of type map[int]int) as map[string]'b value in argument to has"}},
+	}
+
+	for _, tt := range diagnostics {
+		testDiagnostics(t, tt.input, check(t, tt.input), tt.expected)
+	}
+}
+
+func TestSwitchTypes(t *testing.T) {
+	types := `
+	type StatusCode = | Ok | NotFound
+	type Ok = 200
+	type NotFound = 404
+	type Circle = radius: int
+	type Square = side: int
+	type Shape = | Circle | Square
+	`
+
+	tests := []struct {
+		input    string
+		name     string
+		expected string
+	}{
+		{`let s = switch (1) { 1 => "one", _ => "many" }`, "s", "string"},
+		{`let f = func(x) { switch (x) { 1 => true, _ => false } }`, "f", "func(int): bool"},
+		{`let f = func(x) { switch (x) { n => n } }`, "f", "func('a): 'a"},
+		{types + `let f = func(c: StatusCode) { switch (c) { Ok => "ok", NotFound => "missing" } }`, "f", "func(StatusCode): string"},
+		{types + `let area = func(s: Shape) { switch (s) { {radius} => radius * radius * 3, {side} => side * side } }`, "area", "func(Shape): int"},
+		{`let r = {code: 200, body: "hi"}; let b = switch (r) { {code: 404} => "", {body} => body }`, "b", "string"},
+		{`let s = switch (1.0) { 1 => "int", _ => "other" }`, "s", "string"},
+		{`let f = func(x: float) { switch (x) { 0 => "zero", 0.5 => "half", _ => "other" } }`, "f", "func(float): string"},
+		{`let f = func(x: int) { switch (x) { 1.0 => true, _ => false } }`, "f", "func(int): bool"},
+	}
+
+	for _, tt := range tests {
+		checker := New()
+		if diags := checker.Check(parse(t, tt.input)); len(diags) != 0 {
+			t.Errorf("%q: checker had errors: %v", tt.input, diags)
+			continue
+		}
+
+		typ, _ := checker.Scope().LookupValue(tt.name)
+		if typ == nil || typ.String() != tt.expected {
+			t.Errorf("%q: %s has type %v, expected %s", tt.input, tt.name, typ, tt.expected)
+		}
+	}
+
+	diagnostics := []struct {
+		input    string
+		expected []string
+	}{
+		{`switch (1) { _ => 1, 2 => 2 }`, []string{"1:22: warning: unreachable switch arm, _ above matches everything"}},
+		{`switch (1) { n => n, 2 => 2, _ => 3 }`, []string{
+			"1:22: warning: unreachable switch arm, n above matches everything",
+			"1:30: warning: unreachable switch arm, n above matches everything",
+		}},
+		{`switch (1) { 1 => 1, 1 => 2, _ => 3 }`, []string{"1:22: warning: unreachable switch arm, 1 is matched above"}},
+		{`switch (1) { int => 1, _ => 2 }`, []string{"1:24: warning: unreachable switch arm, int above matches everything"}},
+		{`switch ({a: 1}) { {a} => a, _ => 0 }`, []string{"1:29: warning: unreachable switch arm, {a} above matches everything"}},
+		{types + `let f = func(c: StatusCode) { switch (c) { Ok => 1, StatusCode => 2, NotFound => 3 } }`, []string{
+			"8:71: warning: unreachable switch arm, StatusCode above matches everything",
+		}},
+		{`switch (1) { 1 => "a", _ => 2 }`, []string{"1:1: mismatched types string and int in switch arms"}},
+		{`switch (1) { "a" => 1, _ => 2 }`, []string{`1:14: pattern "a" (of type string) can never match int value`}},
+		{`switch (1) { bool => 1, _ => 2 }`, []string{"1:14: pattern bool (of type bool) can never match int value"}},
+		{`switch (1) { {a} => a, _ => 2 }`, []string{"1:14: pattern {a} can never match int value, which lacks its fields"}},
+		{types + `let f = func(s: Shape) { switch (s) { {width} => width } }`, []string{
+			"8:40: pattern {width} can never match Shape value, which lacks its fields",
+		}},
+	}
+
+	for _, tt := range diagnostics {
+		testDiagnostics(t, tt.input, check(t, tt.input), tt.expected)
+	}
+}
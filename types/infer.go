@@ -0,0 +1,276 @@
+package types
+
+import (
+	"strconv"
+
+	"github.com/SCKelemen/oak/ast"
+)
+
+// TypeVar stands in for a type we haven't worked out
+// yet. Unification fills in Instance once something
+// pins the variable down, in the style of Hindley and
+// Milner's algorithm W
+type TypeVar struct {
+	ID    int
+	Level int // the let nesting depth the variable was made at
+
+	// what the variable turned out to be,
+	// and the node which made us decide so
+	Instance Type
+	origin   ast.Node
+}
+
+func (tv *TypeVar) String() string {
+	if tv.Instance != nil {
+		return tv.Instance.String()
+	}
+
+	name := "'" + string(rune('a'+tv.ID%26))
+	if tv.ID >= 26 {
+		name += strconv.Itoa(tv.ID / 26)
+	}
+	return name
+}
+
+// Scheme is a polymorphic type, which every use
+// of a let bound name gets a fresh copy of. This
+// lets let id = func(x) { x } be used with ints
+// and bools alike
+type Scheme struct {
+	Vars []*TypeVar
+	Type Type
+}
+
+// String names the quantified variables 'a, 'b,
+// and so on in the order they appear
+func (s *Scheme) String() string {
+	next := 0
+	return instantiate(s, func() *TypeVar {
+		next++
+		return &TypeVar{ID: next - 1}
+	}).String()
+}
+
+// prune follows bound variables to the type
+// they were bound to
+func prune(t Type) Type {
+	for {
+		tv, ok := t.(*TypeVar)
+		if !ok || tv.Instance == nil {
+			return t
+		}
+		t = tv.Instance
+	}
+}
+
+// origin finds the node which pinned t down, if
+// t is a variable that has since been bound
+func origin(t Type) ast.Node {
+	var node ast.Node
+	for {
+		tv, ok := t.(*TypeVar)
+		if !ok || tv.Instance == nil {
+			return node
+		}
+		node = tv.origin
+		t = tv.Instance
+	}
+}
+
+func (c *Checker) fresh() *TypeVar {
+	c.nextVar++
+	return &TypeVar{ID: c.nextVar - 1, Level: c.level}
+}
+
+// unify makes a and b the same type, binding variables
+// as needed, and reports whether it could. Types which
+// are already known are compatible if either can be
+// used as the other, so int unifies with type Ok = 200
+func (c *Checker) unify(a, b Type, at ast.Node) bool {
+	a, b = prune(a), prune(b)
+	if a == b || a == Unknown || b == Unknown {
+		return true
+	}
+
+	if tv, ok := a.(*TypeVar); ok {
+		return c.bind(tv, b, at)
+	}
+	if tv, ok := b.(*TypeVar); ok {
+		return c.bind(tv, a, at)
+	}
+
+	af, aok := a.(*Function)
+	bf, bok := b.(*Function)
+	if aok && bok {
+		if len(af.Params) != len(bf.Params) {
+			return false
+		}
+		for i := range af.Params {
+			if !c.unify(af.Params[i], bf.Params[i], at) {
+				return false
+			}
+		}
+		return c.unify(af.Result, bf.Result, at)
+	}
+
+	al, aok := a.(*List)
+	bl, bok := b.(*List)
+	if aok && bok {
+		return c.unify(al.Elem, bl.Elem, at)
+	}
+
+	ah, aok := a.(*Hash)
+	bh, bok := b.(*Hash)
+	if aok && bok {
+		return c.unify(ah.Key, bh.Key, at) && c.unify(ah.Value, bh.Value, at)
+	}
+
+	ap, aok := a.(*Property)
+	bp, bok := b.(*Property)
+	if aok && bok && ap.Name == bp.Name {
+		return c.unify(ap.Type, bp.Type, at)
+	}
+
+	// records written with the same fields in the
+	// same order line up field by field
+	ai, aok := a.(*Intersection)
+	bi, bok := b.(*Intersection)
+	if aok && bok && sameFields(ai, bi) {
+		for i := range ai.Types {
+			if !c.unify(ai.Types[i], bi.Types[i], at) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return AssignableTo(a, b) || AssignableTo(b, a)
+}
+
+func sameFields(a, b *Intersection) bool {
+	if len(a.Types) != len(b.Types) {
+		return false
+	}
+	for i := range a.Types {
+		ap, aok := a.Types[i].(*Property)
+		bp, bok := b.Types[i].(*Property)
+		if !aok || !bok || ap.Name != bp.Name {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Checker) bind(tv *TypeVar, t Type, at ast.Node) bool {
+	// func(f) { f(f) } would need an infinite type
+	if occurs(tv, t) {
+		return false
+	}
+
+	// anything t mentions now lives as long as tv does
+	for _, free := range freeVars(t, nil) {
+		if free.Level > tv.Level {
+			free.Level = tv.Level
+		}
+	}
+
+	tv.Instance = t
+	tv.origin = at
+	return true
+}
+
+func occurs(tv *TypeVar, t Type) bool {
+	for _, free := range freeVars(t, nil) {
+		if free == tv {
+			return true
+		}
+	}
+	return false
+}
+
+// freeVars appends the unbound variables of t to vars,
+// in the order they appear, skipping any repeats
+func freeVars(t Type, vars []*TypeVar) []*TypeVar {
+	switch t := prune(t).(type) {
+	case *TypeVar:
+		for _, v := range vars {
+			if v == t {
+				return vars
+			}
+		}
+		return append(vars, t)
+	case *Function:
+		for _, p := range t.Params {
+			vars = freeVars(p, vars)
+		}
+		return freeVars(t.Result, vars)
+	case *List:
+		return freeVars(t.Elem, vars)
+	case *Hash:
+		return freeVars(t.Value, freeVars(t.Key, vars))
+	case *Property:
+		return freeVars(t.Type, vars)
+	case *Intersection:
+		for _, member := range t.Types {
+			vars = freeVars(member, vars)
+		}
+	}
+	return vars
+}
+
+// generalize quantifies over the variables of t made
+// while checking the current let binding, leaving
+// those still shared with the enclosing scope alone
+func (c *Checker) generalize(t Type) Type {
+	vars := []*TypeVar{}
+	for _, v := range freeVars(t, nil) {
+		if v.Level > c.level {
+			vars = append(vars, v)
+		}
+	}
+
+	if len(vars) == 0 {
+		return t
+	}
+	return &Scheme{Vars: vars, Type: t}
+}
+
+// instantiate copies s.Type, replacing each of its
+// quantified variables with one made by fresh
+func instantiate(s *Scheme, fresh func() *TypeVar) Type {
+	subst := map[*TypeVar]Type{}
+	for _, v := range s.Vars {
+		subst[v] = fresh()
+	}
+	return substitute(s.Type, subst)
+}
+
+func substitute(t Type, subst map[*TypeVar]Type) Type {
+	switch t := prune(t).(type) {
+	case *TypeVar:
+		if replacement, ok := subst[t]; ok {
+			return replacement
+		}
+		return t
+	case *Function:
+		fn := &Function{Result: substitute(t.Result, subst)}
+		for _, p := range t.Params {
+			fn.Params = append(fn.Params, substitute(p, subst))
+		}
+		return fn
+	case *List:
+		return &List{Elem: substitute(t.Elem, subst)}
+	case *Hash:
+		return &Hash{Key: substitute(t.Key, subst), Value: substitute(t.Value, subst)}
+	case *Property:
+		return &Property{Name: t.Name, Type: substitute(t.Type, subst)}
+	case *Intersection:
+		intersection := &Intersection{}
+		for _, member := range t.Types {
+			intersection.Types = append(intersection.Types, substitute(member, subst))
+		}
+		return intersection
+	default:
+		return t
+	}
+}
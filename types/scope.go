@@ -0,0 +1,120 @@
+package types
+
+import "github.com/SCKelemen/oak/ast"
+
+// Scope maps names to types. Values and types live
+// in separate namespaces, so let x = 1 and type x = int
+// can happily coexist
+type Scope struct {
+	outer  *Scope
+	values map[string]Type
+	types  map[string]Type
+
+	// where types were declared, for
+	// pointing back at them in errors,
+	// or nil if by an earlier program
+	typeDecls map[string]ast.Node
+}
+
+func NewScope(outer *Scope) *Scope {
+	return &Scope{
+		outer:     outer,
+		values:    map[string]Type{},
+		types:     map[string]Type{},
+		typeDecls: map[string]ast.Node{},
+	}
+}
+
+// LookupValue finds the type of the value
+// bound to name, searching outer scopes
+func (s *Scope) LookupValue(name string) (Type, bool) {
+	for scope := s; scope != nil; scope = scope.outer {
+		if t, ok := scope.values[name]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// LookupType finds the type declared as
+// name, searching outer scopes
+func (s *Scope) LookupType(name string) (Type, bool) {
+	for scope := s; scope != nil; scope = scope.outer {
+		if t, ok := scope.types[name]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Scope) DeclareValue(name string, t Type) { s.values[name] = t }
+func (s *Scope) DeclareType(name string, t Type)  { s.types[name] = t }
+
+// copy gives back a scope with the same declarations
+// as s, which declaring more in leaves s alone
+func (s *Scope) copy() *Scope {
+	scope := NewScope(s.outer)
+	for name, t := range s.values {
+		scope.values[name] = t
+	}
+	for name, t := range s.types {
+		scope.types[name] = t
+	}
+	for name, node := range s.typeDecls {
+		scope.typeDecls[name] = node
+	}
+	return scope
+}
+
+// Universe holds the builtin types and functions,
+// and is the outermost scope of every program
+var Universe = NewScope(nil)
+
+func init() {
+	for _, basic := range []*Basic{Int, Float, Bool, String, Char} {
+		Universe.DeclareType(basic.Name, basic)
+	}
+
+	for name, t := range builtins() {
+		Universe.DeclareValue(name, t)
+	}
+}
+
+// builtins gives the types of the builtin functions.
+// Collections come last so that they can be piped in
+func builtins() map[string]Type {
+	a, b := &TypeVar{ID: 0}, &TypeVar{ID: 1}
+	list := func(elem Type) *List { return &List{Elem: elem} }
+	hash := func(key, value Type) *Hash { return &Hash{Key: key, Value: value} }
+	fn := func(result Type, params ...Type) *Function {
+		return &Function{Params: params, Result: result}
+	}
+	forall := func(t Type, vars ...*TypeVar) *Scheme {
+		return &Scheme{Vars: vars, Type: t}
+	}
+
+	return map[string]Type{
+		"map":       forall(fn(list(b), fn(b, a), list(a)), a, b),
+		"filter":    forall(fn(list(a), fn(Bool, a), list(a)), a),
+		"reduce":    forall(fn(b, fn(b, b, a), b, list(a)), a, b),
+		"take":      forall(fn(list(a), Int, list(a)), a),
+		"limit":     forall(fn(list(a), Int, list(a)), a),
+		"transform": forall(fn(list(b), fn(b, a, Int), list(a)), a, b),
+
+		// there are no tuples, so all we can say
+		// of the pairs is that they are lists
+		"zip": forall(fn(list(list(Unknown)), list(a), list(b)), a, b),
+
+		"first": forall(fn(a, list(a)), a),
+		"rest":  forall(fn(list(a), list(a)), a),
+		"push":  forall(fn(list(a), a, list(a)), a),
+
+		"keys":   forall(fn(list(a), hash(a, b)), a, b),
+		"values": forall(fn(list(b), hash(a, b)), a, b),
+		"has":    forall(fn(Bool, a, hash(a, b)), a, b),
+
+		// len takes strings and lists alike, which
+		// a scheme can't say, so anything goes
+		"len": forall(fn(Int, a), a),
+	}
+}
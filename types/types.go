@@ -0,0 +1,271 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Type is the static counterpart of object.Type,
+// describing the values an expression may have
+type Type interface {
+	String() string
+}
+
+// Basic is one of the builtin types
+type Basic struct {
+	Name string
+}
+
+func (b *Basic) String() string { return b.Name }
+
+var (
+	// Unknown is given to expressions we can't say
+	// anything about, such as untyped arguments, or
+	// ones which already had an error reported. It
+	// is compatible with every other type so that
+	// one mistake doesn't cascade into many
+	Unknown = &Basic{Name: "unknown"}
+
+	Int    = &Basic{Name: "int"}
+	Float  = &Basic{Name: "float"}
+	Bool   = &Basic{Name: "bool"}
+	String = &Basic{Name: "string"}
+	Char   = &Basic{Name: "char"}
+)
+
+// Named is a type introduced by a type declaration
+type Named struct {
+	Name       string
+	Underlying Type
+}
+
+func (n *Named) String() string { return n.Name }
+
+// Literal is a singleton type such as 200
+type Literal struct {
+	Value interface{} // int64 || bool
+	Base  *Basic
+}
+
+func (l *Literal) String() string { return fmt.Sprintf("%v", l.Value) }
+
+type Union struct {
+	Variants []Type
+}
+
+func (u *Union) String() string {
+	var out bytes.Buffer
+
+	for i, v := range u.Variants {
+		if i > 0 {
+			out.WriteRune(' ')
+		}
+		out.WriteString("| ")
+		out.WriteString(v.String())
+	}
+
+	return out.String()
+}
+
+type Intersection struct {
+	Types []Type
+}
+
+func (i *Intersection) String() string {
+	// the type of the empty record, {}
+	if len(i.Types) == 0 {
+		return "{}"
+	}
+
+	types := []string{}
+	for _, t := range i.Types {
+		types = append(types, t.String())
+	}
+	return strings.Join(types, " & ")
+}
+
+type Property struct {
+	Name string
+	Type Type
+}
+
+func (p *Property) String() string { return p.Name + ": " + p.Type.String() }
+
+type Function struct {
+	Params []Type
+	Result Type
+}
+
+func (f *Function) String() string {
+	params := []string{}
+	for _, p := range f.Params {
+		params = append(params, p.String())
+	}
+	return "func(" + strings.Join(params, ", ") + "): " + f.Result.String()
+}
+
+// List is a sequence of values all of type Elem
+type List struct {
+	Elem Type
+}
+
+func (l *List) String() string { return "[]" + l.Elem.String() }
+
+// Hash maps keys of type Key to values of type Value
+type Hash struct {
+	Key, Value Type
+}
+
+func (h *Hash) String() string { return "map[" + h.Key.String() + "]" + h.Value.String() }
+
+// Underlying strips away names and literal values,
+// leaving the basic type operators work on. Unions
+// whose variants all share a basic type reduce to
+// it, anything else is returned as is
+func Underlying(t Type) Type {
+	return underlying(t, map[*Named]bool{})
+}
+
+func underlying(t Type, seen map[*Named]bool) Type {
+	switch t := prune(t).(type) {
+	case *Named:
+		if seen[t] || t.Underlying == nil {
+			return Unknown
+		}
+		seen[t] = true
+		return underlying(t.Underlying, seen)
+	case *Literal:
+		return t.Base
+	case *Union:
+		var base Type
+		for _, v := range t.Variants {
+			u := underlying(v, seen)
+			if base != nil && u != base {
+				return t
+			}
+			base = u
+		}
+		if base == nil {
+			return t
+		}
+		return base
+	}
+	return prune(t)
+}
+
+// AssignableTo reports whether a value of type v
+// may be used where a value of type t is wanted
+func AssignableTo(v, t Type) bool {
+	return assignable(v, t, map[[2]Type]bool{})
+}
+
+func assignable(v, t Type, seen map[[2]Type]bool) bool {
+	v, t = prune(v), prune(t)
+	if v == t || v == Unknown || t == Unknown {
+		return true
+	}
+
+	// assume recursive types line up, if they
+	// don't we'll find out somewhere else
+	pair := [2]Type{v, t}
+	if seen[pair] {
+		return true
+	}
+	seen[pair] = true
+
+	// unions on the left need every variant to fit,
+	// so check them before looking at the right
+	if u, ok := v.(*Union); ok {
+		for _, variant := range u.Variants {
+			if !assignable(variant, t, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	if n, ok := v.(*Named); ok && n.Underlying != nil {
+		if _, isUnion := n.Underlying.(*Union); isUnion {
+			return assignable(n.Underlying, t, seen)
+		}
+	}
+
+	switch target := t.(type) {
+	case *Named:
+		if target.Underlying == nil {
+			return false
+		}
+		return assignable(v, target.Underlying, seen)
+
+	case *Union:
+		for _, variant := range target.Variants {
+			if assignable(v, variant, seen) {
+				return true
+			}
+		}
+		return false
+
+	case *Intersection:
+		for _, member := range target.Types {
+			if !assignable(v, member, seen) {
+				return false
+			}
+		}
+		return true
+
+	case *Literal:
+		switch value := v.(type) {
+		case *Literal:
+			return value.Value == target.Value
+		case *Named:
+			return value.Underlying != nil && assignable(value.Underlying, t, seen)
+		}
+		return false
+
+	case *List:
+		list, ok := v.(*List)
+		return ok && assignable(list.Elem, target.Elem, seen)
+
+	case *Hash:
+		hash, ok := v.(*Hash)
+		return ok && assignable(hash.Key, target.Key, seen) && assignable(target.Key, hash.Key, seen) &&
+			assignable(hash.Value, target.Value, seen)
+
+	case *Function:
+		fn, ok := v.(*Function)
+		if !ok || len(fn.Params) != len(target.Params) {
+			return false
+		}
+		for i := range fn.Params {
+			if !assignable(target.Params[i], fn.Params[i], seen) {
+				return false
+			}
+		}
+		return assignable(fn.Result, target.Result, seen)
+	}
+
+	// t is a basic type or property, anything
+	// which reduces to the same thing will do
+	switch value := v.(type) {
+	case *Named:
+		return value.Underlying != nil && assignable(value.Underlying, t, seen)
+	case *Literal:
+		return value.Base == t
+	case *Intersection:
+		for _, member := range value.Types {
+			if assignable(member, t, seen) {
+				return true
+			}
+		}
+		return false
+	case *Property:
+		p, ok := t.(*Property)
+		return ok && p.Name == value.Name && assignable(value.Type, p.Type, seen)
+	}
+	return false
+}
+
+// Identical reports whether v and t are the same type
+func Identical(v, t Type) bool {
+	return AssignableTo(v, t) && AssignableTo(t, v)
+}